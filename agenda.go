@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"transcribe/pkg/transcribe"
+)
+
+// loadAgenda reads an agenda file where each line is "<timestamp> <heading>",
+// e.g. "00:15 Budget review". Blank lines and lines starting with # are ignored.
+func loadAgenda(filename string) ([]transcribe.AgendaItem, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open agenda: %w", err)
+	}
+	defer file.Close()
+
+	var items []transcribe.AgendaItem
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		stamp, heading, _ := strings.Cut(line, " ")
+		start, err := parseTimestamp(stamp)
+		if err != nil {
+			return nil, fmt.Errorf("agenda line %d: %w", lineNo, err)
+		}
+		items = append(items, transcribe.AgendaItem{Start: start, Heading: strings.TrimSpace(heading)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read agenda: %w", err)
+	}
+
+	for i := 1; i < len(items); i++ {
+		if items[i].Start < items[i-1].Start {
+			return nil, fmt.Errorf("agenda items are not in chronological order: %q", items[i].Heading)
+		}
+	}
+
+	return items, nil
+}
+
+// parseTimestamp parses HH:MM:SS, MM:SS or plain seconds into a duration
+func parseTimestamp(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp: %s", s)
+	}
+
+	var total time.Duration
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid timestamp: %s", s)
+		}
+		total = total*60 + time.Duration(n)
+	}
+	return total * time.Second, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// apiKeyVariable is the environment variable and .env entry holding the API key
+const apiKeyVariable = "ASSEMBLYAI_API_KEY"
+
+// keychainService is the service the API key is stored under in the OS keychain:
+//
+//	security add-generic-password -s transcribe -a assemblyai -w   (macOS)
+//	secret-tool store --label transcribe service transcribe        (Linux)
+const keychainService = "transcribe"
+
+// apiKeyUsage is the usage of the --api-key flag of the commands that call the API
+const apiKeyUsage = "AssemblyAI API key (default: $" + apiKeyVariable + ", the OS keychain, then .env)"
+
+// loadAPIKey returns the AssemblyAI API key. See resolveAPIKey.
+func loadAPIKey(flagValue string) (string, error) {
+	apiKey, _, err := resolveAPIKey(flagValue)
+	return apiKey, err
+}
+
+// resolveAPIKey returns the API key and where it was found, trying in order the
+// --api-key value, the environment, the OS keychain and a .env file in the
+// current directory
+func resolveAPIKey(flagValue string) (string, string, error) {
+	if flagValue != "" {
+		return flagValue, "--api-key", nil
+	}
+	if apiKey := os.Getenv(apiKeyVariable); apiKey != "" {
+		return apiKey, "environment", nil
+	}
+	if apiKey, err := keychainAPIKey(); err == nil && apiKey != "" {
+		return apiKey, "keychain", nil
+	}
+
+	env, err := godotenv.Read()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", "", fmt.Errorf("loading .env file: %w", err)
+	}
+	if apiKey := env[apiKeyVariable]; apiKey != "" {
+		return apiKey, ".env", nil
+	}
+	return "", "", fmt.Errorf("no API key found; pass --api-key, set %s, store it in the keychain under the service %q or add it to .env", apiKeyVariable, keychainService)
+}
+
+// keychainAPIKey reads the API key from the OS keychain with the platform's
+// command line client
+func keychainAPIKey() (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-w")
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", keychainService)
+	default:
+		return "", errors.New("no keychain client for this platform")
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("keychain lookup failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"transcribe/pkg/transcribe"
+)
+
+// autoNameKeywords is the number of content words used in a generated name
+const autoNameKeywords = 3
+
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// stopwords are common words that say nothing about what a recording is about
+var stopwords = map[string]bool{
+	"about": true, "after": true, "again": true, "all": true, "also": true, "and": true,
+	"any": true, "are": true, "back": true, "because": true, "been": true, "before": true,
+	"being": true, "but": true, "can": true, "could": true, "did": true, "does": true,
+	"doing": true, "don": true, "dont": true, "down": true, "even": true, "for": true,
+	"from": true, "get": true, "going": true, "gonna": true, "good": true, "got": true,
+	"had": true, "has": true, "have": true, "her": true, "here": true, "him": true,
+	"his": true, "how": true, "into": true, "its": true, "just": true, "know": true,
+	"like": true, "look": true, "make": true, "maybe": true, "mean": true, "more": true,
+	"much": true, "need": true, "not": true, "now": true, "okay": true, "one": true,
+	"only": true, "other": true, "our": true, "out": true, "over": true, "really": true,
+	"right": true, "said": true, "say": true, "see": true, "she": true, "should": true,
+	"some": true, "something": true, "still": true, "such": true, "sure": true, "take": true,
+	"than": true, "thank": true, "thanks": true, "that": true, "the": true, "their": true,
+	"them": true, "then": true, "there": true, "these": true, "they": true, "thing": true,
+	"things": true, "think": true, "this": true, "those": true, "through": true, "too": true,
+	"uh": true, "um": true, "very": true, "want": true, "was": true, "way": true,
+	"well": true, "were": true, "what": true, "when": true, "where": true, "which": true,
+	"while": true, "who": true, "why": true, "will": true, "with": true, "would": true,
+	"yeah": true, "yes": true, "you": true, "your": true,
+}
+
+// autoName builds a descriptive slug such as "2024-05-02-q3-pricing-review" from the
+// recording date and the most frequent content words of the transcript
+func autoName(date time.Time, transcription *TranscriptionResponse) string {
+	parts := []string{date.Format("2006-01-02")}
+	parts = append(parts, topKeywords(transcribe.TranscriptText(transcription), autoNameKeywords)...)
+	return strings.Join(parts, "-")
+}
+
+// topKeywords returns up to n of the most frequent non-stopwords in text, in the
+// order they first appear
+func topKeywords(text string, n int) []string {
+	counts := make(map[string]int)
+	firstSeen := make(map[string]int)
+	for i, word := range wordPattern.FindAllString(strings.ToLower(text), -1) {
+		if len([]rune(word)) < 3 || stopwords[word] {
+			continue
+		}
+		if _, ok := firstSeen[word]; !ok {
+			firstSeen[word] = i
+		}
+		counts[word]++
+	}
+
+	words := make([]string, 0, len(counts))
+	for word := range counts {
+		words = append(words, word)
+	}
+	slices.SortFunc(words, func(a, b string) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return firstSeen[a] - firstSeen[b]
+	})
+
+	words = words[:min(n, len(words))]
+	slices.SortFunc(words, func(a, b string) int { return firstSeen[a] - firstSeen[b] })
+	return words
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// downmixFilter returns an FFmpeg filter that folds audio with more than two
+// channels down to mono, and the reason to log, or "" when the layout is fine as
+// is. The API only handles mono and stereo reliably and otherwise mixes channels
+// without telling.
+func downmixFilter(stream audioStream) (string, string) {
+	if stream.Channels <= 2 {
+		return "", ""
+	}
+	if strings.HasPrefix(stream.ChannelLayout, "5.1") || strings.HasPrefix(stream.ChannelLayout, "7.1") || stream.ChannelLayout == "6.1" {
+		// Dialogue sits in the centre channel; the surrounds and LFE are mostly noise
+		return "pan=mono|c0<FC+0.5*FL+0.5*FR",
+			fmt.Sprintf("downmixing %s surround audio to mono from the centre and front channels", stream.ChannelLayout)
+	}
+	channels := make([]int, stream.Channels)
+	for i := range channels {
+		channels[i] = i
+	}
+	return selectChannelsFilter(channels),
+		fmt.Sprintf("mixing %d channels to mono; use --channels to pick the microphones to keep", stream.Channels)
+}
+
+// selectChannelsFilter returns an FFmpeg filter that mixes the given zero-based
+// channels into mono at equal gain
+func selectChannelsFilter(channels []int) string {
+	terms := make([]string, len(channels))
+	for i, c := range channels {
+		terms[i] = fmt.Sprintf("c%d", c)
+	}
+	return "pan=mono|c0<" + strings.Join(terms, "+")
+}
+
+// parseChannels parses a comma-separated list of one-based channel numbers into
+// zero-based indexes
+func parseChannels(value string) ([]int, error) {
+	var channels []int
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid channel: %s", part)
+		}
+		channels = append(channels, n-1)
+	}
+	return channels, nil
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"transcribe/pkg/transcribe"
+)
+
+const (
+	// coachSampleRate is the rate audio is decoded at for pitch tracking
+	coachSampleRate = 8000
+	// coachFrameSize is the number of samples per pitch frame (40 ms)
+	coachFrameSize = coachSampleRate / 25
+	// coachMinPitch and coachMaxPitch bound the fundamental frequency searched for,
+	// covering low male to high female and child voices
+	coachMinPitch = 60
+	coachMaxPitch = 400
+	// coachPaceWindow is the span each point of the pace timeline covers
+	coachPaceWindow = time.Minute
+	// coachSlowWPM and coachFastWPM bound the comfortable presentation pace
+	coachSlowWPM = 110
+	coachFastWPM = 170
+)
+
+// pauseBuckets are the upper bounds of the pause distribution classes in ms; the
+// last class is open-ended
+var pauseBuckets = []int{500, 1000, 2000, 5000}
+
+// coachSpeaker holds the delivery figures of one speaker
+type coachSpeaker struct {
+	Speaker      string
+	Words        int
+	SpeakingTime time.Duration
+	WPM          float64
+	Fillers      int
+	// FillerRate is the number of fillers per 100 words
+	FillerRate float64
+	HasPitch   bool
+	PitchLow   float64
+	PitchMid   float64
+	PitchHigh  float64
+	// PitchRange is the spread between PitchLow and PitchHigh in semitones
+	PitchRange float64
+}
+
+// paceWindow is a point of the pace timeline
+type paceWindow struct {
+	Start string
+	WPM   float64
+	// Width is the bar length relative to the fastest window
+	Width float64
+	Note  string
+}
+
+// pauseClass is a class of the pause distribution
+type pauseClass struct {
+	Label string
+	Count int
+	Share float64
+}
+
+// coachReport holds the figures rendered on the coaching report
+type coachReport struct {
+	Speakers     []coachSpeaker
+	Pace         []paceWindow
+	Pauses       []pauseClass
+	LongestPause time.Duration
+	SlowWPM      int
+	FastWPM      int
+}
+
+var coachReportPage = template.Must(template.New("coach").Funcs(template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+	"round":   func(d time.Duration) time.Duration { return d.Round(time.Second) },
+	"fixed":   func(f float64) string { return fmt.Sprintf("%.0f", f) },
+	"decimal": func(f float64) string { return fmt.Sprintf("%.1f", f) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Presentation coaching report</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.8em; text-align: left; }
+.bar { background: #1f77b4; height: 1em; }
+.note { color: #d62728; }
+</style>
+</head>
+<body>
+<h1>Presentation coaching report</h1>
+<h2>Delivery</h2>
+<table>
+<tr><th>Speaker</th><th>Speaking time</th><th>Words</th><th>Words per minute</th><th>Fillers</th><th>Fillers per 100 words</th><th>Pitch (low / median / high)</th><th>Pitch range</th></tr>
+{{range .Speakers}}<tr><td>{{.Speaker}}</td><td>{{round .SpeakingTime}}</td><td>{{.Words}}</td><td>{{fixed .WPM}}</td><td>{{.Fillers}}</td><td>{{decimal .FillerRate}}</td>{{if .HasPitch}}<td>{{fixed .PitchLow}} / {{fixed .PitchMid}} / {{fixed .PitchHigh}} Hz</td><td>{{decimal .PitchRange}} semitones</td>{{else}}<td>n/a</td><td>n/a</td>{{end}}</tr>
+{{end}}</table>
+<h2>Pace over time</h2>
+<p>Words per minute of speaking time; {{.SlowWPM}}-{{.FastWPM}} is a comfortable pace for presentations.</p>
+<table>
+<tr><th>From</th><th>Words per minute</th><th style="width: 20em"></th><th></th></tr>
+{{range .Pace}}<tr><td>{{.Start}}</td><td>{{fixed .WPM}}</td><td><div class="bar" style="width: {{percent .Width}}"></div></td><td class="note">{{.Note}}</td></tr>
+{{end}}</table>
+<h2>Pauses</h2>
+<p>Silences between segments. Longest: {{round .LongestPause}}.</p>
+<table>
+<tr><th>Length</th><th>Count</th><th style="width: 20em"></th></tr>
+{{range .Pauses}}<tr><td>{{.Label}}</td><td>{{.Count}}</td><td><div class="bar" style="width: {{percent .Share}}"></div></td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// buildCoachReport computes pace, pauses and filler density from the utterances,
+// with the pitch samples of each speaker in Hz when available. The API gives no
+// word timings here, so words are spread evenly over their segment for the pace
+// timeline, and pauses within a segment are not seen.
+func buildCoachReport(utterances []Utterance, pitches map[string][]float64) coachReport {
+	report := coachReport{SlowWPM: coachSlowWPM, FastWPM: coachFastWPM}
+	index := make(map[string]int)
+	windowWords := make(map[int]float64)
+	windowTime := make(map[int]float64)
+	lastWindow := 0
+
+	for _, utterance := range utterances {
+		i, ok := index[utterance.Speaker]
+		if !ok {
+			i = len(report.Speakers)
+			index[utterance.Speaker] = i
+			report.Speakers = append(report.Speakers, coachSpeaker{Speaker: "Speaker " + utterance.Speaker})
+		}
+		s := &report.Speakers[i]
+		words := wordPattern.FindAllString(strings.ToLower(utterance.Text), -1)
+		s.Words += len(words)
+		s.Fillers += fillerCount(words)
+		duration := utterance.End - utterance.Start
+		if duration <= 0 {
+			continue
+		}
+		s.SpeakingTime += time.Duration(duration) * time.Millisecond
+
+		// Share the words out over the windows the segment overlaps
+		window := int(coachPaceWindow.Milliseconds())
+		for w := utterance.Start / window; w*window < utterance.End; w++ {
+			overlap := min(utterance.End, (w+1)*window) - max(utterance.Start, w*window)
+			windowTime[w] += float64(overlap)
+			windowWords[w] += float64(len(words)) * float64(overlap) / float64(duration)
+			lastWindow = max(lastWindow, w)
+		}
+	}
+
+	for i := range report.Speakers {
+		s := &report.Speakers[i]
+		if s.SpeakingTime > 0 {
+			s.WPM = float64(s.Words) / s.SpeakingTime.Minutes()
+		}
+		if s.Words > 0 {
+			s.FillerRate = 100 * float64(s.Fillers) / float64(s.Words)
+		}
+		samples := slices.Sorted(slices.Values(pitches[strings.TrimPrefix(s.Speaker, "Speaker ")]))
+		if len(samples) >= 10 {
+			s.HasPitch = true
+			s.PitchLow = samples[len(samples)/10]
+			s.PitchMid = samples[len(samples)/2]
+			s.PitchHigh = samples[len(samples)*9/10]
+			s.PitchRange = 12 * math.Log2(s.PitchHigh/s.PitchLow)
+		}
+	}
+
+	maxWPM := 0.0
+	for w := 0; w <= lastWindow && len(windowTime) > 0; w++ {
+		pw := paceWindow{Start: transcribe.FormatTimestamp((time.Duration(w) * coachPaceWindow).Seconds())}
+		// Windows with under 10 seconds of speech give no meaningful pace
+		if windowTime[w] >= 10000 {
+			pw.WPM = windowWords[w] / (windowTime[w] / 60000)
+			switch {
+			case pw.WPM < coachSlowWPM:
+				pw.Note = "slow"
+			case pw.WPM > coachFastWPM:
+				pw.Note = "fast"
+			}
+		}
+		maxWPM = max(maxWPM, pw.WPM)
+		report.Pace = append(report.Pace, pw)
+	}
+	for i := range report.Pace {
+		if maxWPM > 0 {
+			report.Pace[i].Width = report.Pace[i].WPM / maxWPM
+		}
+	}
+
+	counts := make([]int, len(pauseBuckets)+1)
+	total := 0
+	for i := 1; i < len(utterances); i++ {
+		gap := utterances[i].Start - utterances[i-1].End
+		if gap <= 0 {
+			continue
+		}
+		report.LongestPause = max(report.LongestPause, time.Duration(gap)*time.Millisecond)
+		class, _ := slices.BinarySearch(pauseBuckets, gap)
+		counts[class]++
+		total++
+	}
+	for i, count := range counts {
+		label := fmt.Sprintf("over %gs", float64(pauseBuckets[len(pauseBuckets)-1])/1000)
+		if i < len(pauseBuckets) {
+			label = fmt.Sprintf("up to %gs", float64(pauseBuckets[i])/1000)
+		}
+		class := pauseClass{Label: label, Count: count}
+		if total > 0 {
+			class.Share = float64(count) / float64(total)
+		}
+		report.Pauses = append(report.Pauses, class)
+	}
+	return report
+}
+
+// readPitch decodes audioFile and estimates the pitch of every voiced 40 ms frame
+// inside an utterance by autocorrelation, returning the estimates in Hz per speaker
+func readPitch(audioFile string, utterances []Utterance) (map[string][]float64, error) {
+	cmd := exec.Command("ffmpeg", "-i", audioFile, "-vn", "-ac", "1", "-ar", fmt.Sprint(coachSampleRate), "-f", "s16le", "-")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	pitches := make(map[string][]float64)
+	reader := bufio.NewReader(stdout)
+	block := make([]int16, coachFrameSize)
+	frame := make([]float64, coachFrameSize)
+	next := 0
+	for n := 0; ; n++ {
+		err := binary.Read(reader, binary.LittleEndian, block)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			cmd.Wait()
+			return nil, fmt.Errorf("failed to read audio: %w", err)
+		}
+
+		ms := n * coachFrameSize * 1000 / coachSampleRate
+		for next < len(utterances) && utterances[next].End <= ms {
+			next++
+		}
+		if next == len(utterances) || utterances[next].Start > ms {
+			continue
+		}
+		for i, sample := range block {
+			frame[i] = float64(sample) / 32768
+		}
+		if pitch, ok := framePitch(frame); ok {
+			speaker := utterances[next].Speaker
+			pitches[speaker] = append(pitches[speaker], pitch)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w", err)
+	}
+	return pitches, nil
+}
+
+// framePitch returns the fundamental frequency of a frame, or false for frames
+// that are too quiet or not periodic enough to be voiced speech
+func framePitch(frame []float64) (float64, bool) {
+	energy := 0.0
+	for _, x := range frame {
+		energy += x * x
+	}
+	if math.Sqrt(energy/float64(len(frame))) < 0.01 {
+		return 0, false
+	}
+
+	minLag := coachSampleRate / coachMaxPitch
+	correlations := make([]float64, 0, coachSampleRate/coachMinPitch-minLag+1)
+	best := 0.0
+	for lag := minLag; lag <= coachSampleRate/coachMinPitch && lag < len(frame); lag++ {
+		sum, a, b := 0.0, 0.0, 0.0
+		for i := 0; i+lag < len(frame); i++ {
+			sum += frame[i] * frame[i+lag]
+			a += frame[i] * frame[i]
+			b += frame[i+lag] * frame[i+lag]
+		}
+		r := 0.0
+		if a > 0 && b > 0 {
+			r = sum / math.Sqrt(a*b)
+		}
+		correlations = append(correlations, r)
+		best = max(best, r)
+	}
+	// Weakly periodic frames are unvoiced sounds or noise
+	if best < 0.6 {
+		return 0, false
+	}
+	// Multiples of the period correlate about as well as the period itself, so
+	// take the peak of the shortest lag close to the best to avoid octave errors
+	for i, r := range correlations {
+		if r >= 0.9*best {
+			for i+1 < len(correlations) && correlations[i+1] > correlations[i] {
+				i++
+			}
+			return float64(coachSampleRate) / float64(minLag+i), true
+		}
+	}
+	return 0, false
+}
+
+// saveCoachReport renders the coaching report of the transcription as HTML. The
+// pitch figures are left out, with a warning, when the audio cannot be analyzed.
+func saveCoachReport(filename, audioFile string, transcription *TranscriptionResponse) error {
+	if len(transcription.Utterances) == 0 {
+		return fmt.Errorf("transcript has no speaker segments")
+	}
+
+	pitches, err := readPitch(audioFile, transcription.Utterances)
+	if err != nil {
+		warnf("pitch analysis failed, leaving it out of the coaching report: %v", err)
+	}
+
+	var page bytes.Buffer
+	if err := coachReportPage.Execute(&page, buildCoachReport(transcription.Utterances, pitches)); err != nil {
+		return fmt.Errorf("failed to render coaching report: %w", err)
+	}
+	return os.WriteFile(filename, page.Bytes(), 0644)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// command is a subcommand of the CLI
+type command struct {
+	// Name is the first argument that selects the command
+	Name string
+	// Usage lists the arguments after the name
+	Usage string
+	// Failure prefixes the error printed when Run fails
+	Failure string
+	Run     func(args []string) error
+}
+
+// commands lists the subcommands in the order of the usage message. It is filled
+// in init because the help command prints it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{Name: "run", Usage: "[flags] <video-file>", Failure: "Error", Run: func(args []string) error {
+			runTranscription(args)
+			return nil
+		}},
+		{Name: "convert", Usage: "[flags] <video-file> [output.mp3]", Failure: "Error converting media", Run: runConvert},
+		{Name: "summarize", Usage: "[--prompt TEXT] <transcript.json>", Failure: "Error summarizing transcript", Run: runSummarize},
+		{Name: "serve", Usage: "[--addr host:port] <dir>", Failure: "Error serving files", Run: runServe},
+		{Name: "doctor", Usage: "", Failure: "Error", Run: runDoctor},
+		{Name: "waveform", Usage: "[flags] <transcript.json> <audio-file>", Failure: "Error rendering waveform", Run: runWaveform},
+		{Name: "follow", Usage: "<transcript.json> <audio-file>", Failure: "Error following transcript", Run: runFollow},
+		{Name: "vocab", Usage: "[flags] <transcript.json>", Failure: "Error analyzing vocabulary", Run: runVocab},
+		{Name: "verify", Usage: "[--key minisign.pub] <transcript> [media-file]", Failure: "Error verifying transcript", Run: runVerify},
+		{Name: "terminology", Usage: "list <file> | confirm <file> <term>... | variant <file> <term> <misspelling>...", Failure: "Error editing terminology", Run: runTerminology},
+		{Name: "profile", Usage: "list | save --filter FILTER [--match PATTERN]... <name> | delete <name>", Failure: "Error managing profiles", Run: runProfile},
+		{Name: "reprocess", Usage: "<run-id> [--raw-dir dir] [flags] <video-file>", Failure: "Error", Run: func(args []string) error {
+			// Continue as a regular run that starts from the stored response
+			args, err := reprocessArgs(args)
+			if err != nil {
+				return err
+			}
+			runTranscription(args)
+			return nil
+		}},
+		{Name: "fix", Usage: "--range HH:MM:SS-HH:MM:SS [flags] <transcript.json> [audio-file]", Failure: "Error fixing transcript", Run: runFix},
+		{Name: "feed", Usage: "generate --base-url URL [flags] <transcript.json>...", Failure: "Error generating feed", Run: runFeed},
+		{Name: "publish", Usage: "--to hf-dataset [flags] <org/name> <transcript.json>...", Failure: "Error publishing dataset", Run: runPublish},
+		{Name: "help", Failure: "Error", Run: func(args []string) error {
+			printUsage()
+			fmt.Fprintln(os.Stderr, "\nSee transcribe <command> -h for the flags of a command.")
+			return nil
+		}},
+	}
+}
+
+// findCommand returns the subcommand called name, or nil
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// printUsage prints the usage line of every subcommand. A run without a
+// subcommand is a transcription run, as with "run".
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: transcribe [flags] <video-file>")
+	for _, cmd := range commands {
+		fmt.Fprintln(os.Stderr, strings.TrimRight("       transcribe "+cmd.Name+" "+cmd.Usage, " "))
+	}
+}
+
+// runConvert implements the convert subcommand: it converts media to the MP3
+// that a run would upload, without transcribing it
+func runConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	lowPower := fs.Bool("low-power", false, "single-threaded 16 kHz mono conversion at a lower bitrate")
+	channels := fs.String("channels", "", "mix only these comma-separated channels (1-based) to mono")
+	audioFilter := fs.String("filter", "", "FFmpeg audio filter graph applied before encoding")
+	titleFlag := fs.Int("title", -1, "convert this title (program) of a multi-title container")
+	audioTrack := fs.Int("audio-track", -1, "convert this audio track (0-based)")
+	positional := parseArgs(fs, args)
+	if len(positional) < 1 || len(positional) > 2 {
+		return errors.New("usage: transcribe convert [flags] <video-file> [output.mp3]")
+	}
+
+	opts := convertOptions{LowPower: *lowPower, Map: trackMap(*titleFlag, *audioTrack), AudioFilter: *audioFilter}
+	if *channels != "" {
+		selected, err := parseChannels(*channels)
+		if err != nil {
+			return err
+		}
+		opts.AudioFilter = chainFilters(selectChannelsFilter(selected), opts.AudioFilter)
+	}
+
+	output := strings.TrimSuffix(positional[0], filepath.Ext(positional[0])) + ".mp3"
+	if len(positional) == 2 {
+		output = positional[1]
+	}
+	if output == positional[0] {
+		return errors.New("the input is an MP3 already; give an output file")
+	}
+
+	fmt.Println("Converting video to MP3...")
+	mp3File, err := convertToMP3(positional[0], opts)
+	if err != nil {
+		return err
+	}
+	if err := moveFile(mp3File, output); err != nil {
+		os.Remove(mp3File)
+		return err
+	}
+	fmt.Printf("Audio saved to: %s\n", output)
+	return nil
+}
+
+// moveFile renames src to dst, copying when they are on different file systems
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// summaryPrompt is the default LeMUR prompt of the summarize subcommand
+const summaryPrompt = `Summarize the transcript in a short paragraph, followed by a bulleted list
+of the key points and decisions. Respond in the language of the transcript.`
+
+// runSummarize implements the summarize subcommand: it asks LeMUR to summarize a
+// transcript that is still stored at AssemblyAI
+func runSummarize(args []string) error {
+	fs := flag.NewFlagSet("summarize", flag.ExitOnError)
+	prompt := fs.String("prompt", summaryPrompt, "instructions for the summary")
+	model := fs.String("model", "", "LeMUR model (default: the API default)")
+	out := fs.String("out", "", "write the summary to this `file` instead of stdout")
+	apiKeyFlag := fs.String("api-key", "", apiKeyUsage)
+	fs.StringVar(&apiURL, "api-url", "", apiURLUsage)
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 {
+		return errors.New("usage: transcribe summarize [--prompt TEXT] <transcript.json>")
+	}
+
+	policy, err := enforcePolicy(fs)
+	if err != nil {
+		return err
+	}
+	if policy.strict() {
+		return fmt.Errorf("summarize sends the transcript to LeMUR and is not available with the strict privacy of %s", systemPolicyFile)
+	}
+
+	transcription, err := loadTranscription(positional[0])
+	if err != nil {
+		return err
+	}
+	if transcription.ID == "" {
+		return errors.New("transcript has no ID to summarize at AssemblyAI")
+	}
+	if err := checkAPIURL(); err != nil {
+		return err
+	}
+	apiKey, err := loadAPIKey(*apiKeyFlag)
+	if err != nil {
+		return err
+	}
+
+	summary, err := runLemurTask(transcription.ID, *prompt, *model, apiKey)
+	if err != nil {
+		return err
+	}
+	summary = strings.TrimSpace(summary) + "\n"
+	if *out == "" {
+		fmt.Print(summary)
+		return nil
+	}
+	if err := os.WriteFile(*out, []byte(summary), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Summary saved to: %s\n", *out)
+	return nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// projectConfigFile is the name of the project-local configuration, looked up in
+// the current directory and its parents
+const projectConfigFile = ".transcriberc"
+
+// configFiles returns the configuration files that exist, in the order they are
+// applied: the user configuration, then the nearest project configuration
+func configFiles() []string {
+	var files []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		if file := filepath.Join(dir, "transcribe", "config.toml"); fileExists(file) {
+			files = append(files, file)
+		}
+	}
+	if dir, err := os.Getwd(); err == nil {
+		for {
+			if file := filepath.Join(dir, projectConfigFile); fileExists(file) {
+				files = append(files, file)
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	return files
+}
+
+// fileExists reports whether filename exists
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+// applyConfig sets the flags of fs that were not given on the command line from
+// the configuration files. Settings are named after the flags, e.g.
+//
+//	format = "srt,vtt"
+//	model-fallback = ["best", "nano"]
+//	speakers = 2
+//
+// Arrays set a repeatable flag once per element and any other flag to the
+// comma-separated elements.
+func applyConfig(fs *flag.FlagSet) error {
+	// Aliases such as -o and --output share a value, so a setting is explicit when
+	// its value was set under any name
+	explicit := make(map[flag.Value]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Value] = true })
+
+	for _, file := range configFiles() {
+		settings, err := parseConfig(file)
+		if err != nil {
+			return err
+		}
+		for _, setting := range settings {
+			f := fs.Lookup(setting.Key)
+			if f == nil {
+				return fmt.Errorf("%s:%d: unknown setting %s", file, setting.Line, setting.Key)
+			}
+			if explicit[f.Value] {
+				continue
+			}
+
+			values := setting.Values
+			if list, ok := f.Value.(*stringList); ok {
+				// A later file replaces the list of an earlier one
+				*list = nil
+			} else {
+				values = []string{strings.Join(values, ",")}
+			}
+			for _, value := range values {
+				if err := fs.Set(setting.Key, value); err != nil {
+					return fmt.Errorf("%s:%d: %w", file, setting.Line, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// configSetting is a key and its values read from a configuration file
+type configSetting struct {
+	Key    string
+	Values []string
+	Line   int
+}
+
+// parseConfig reads the subset of TOML used by the configuration files: top-level
+// keys with string, number, boolean or single-line array values, and comments
+func parseConfig(filename string) ([]configSetting, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	defer file.Close()
+
+	var settings []configSetting
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(stripConfigComment(scanner.Text()))
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "[") {
+			return nil, fmt.Errorf("%s:%d: tables are not supported; settings are top-level flag names", filename, line)
+		}
+
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", filename, line)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		values, err := parseConfigValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, line, err)
+		}
+		settings = append(settings, configSetting{Key: key, Values: values, Line: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return settings, nil
+}
+
+// stripConfigComment removes a # comment that is not inside a string
+func stripConfigComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0 && r == quote && (quote == '\'' || i == 0 || line[i-1] != '\\'):
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseConfigValue parses a value into the strings passed to flag.Set: one for a
+// scalar, one per element for an array
+func parseConfigValue(value string) ([]string, error) {
+	if strings.HasPrefix(value, "[") {
+		if !strings.HasSuffix(value, "]") {
+			return nil, errors.New("arrays must be on one line")
+		}
+		var values []string
+		for _, element := range splitConfigArray(value[1 : len(value)-1]) {
+			if element = strings.TrimSpace(element); element == "" {
+				continue
+			}
+			v, err := parseConfigScalar(element)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+	v, err := parseConfigScalar(value)
+	if err != nil {
+		return nil, err
+	}
+	return []string{v}, nil
+}
+
+// splitConfigArray splits array elements at commas outside strings
+func splitConfigArray(text string) []string {
+	var elements []string
+	var quote rune
+	start := 0
+	for i, r := range text {
+		switch {
+		case quote != 0 && r == quote && (quote == '\'' || text[i-1] != '\\'):
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == ',':
+			elements = append(elements, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(elements, text[start:])
+}
+
+// parseConfigScalar parses a string, number or boolean
+func parseConfigScalar(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", value)
+		}
+		return s, nil
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", fmt.Errorf("invalid string %s", value)
+		}
+		return value[1 : len(value)-1], nil
+	case value == "true" || value == "false":
+		return value, nil
+	}
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(value, "_", ""), 64); err != nil {
+		return "", fmt.Errorf("invalid value %s; quote strings", value)
+	}
+	return strings.ReplaceAll(value, "_", ""), nil
+}
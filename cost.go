@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPricePerHour is the assumed price in USD of one hour of audio when a
+// speech model has no entry in modelPricePerHour
+const defaultPricePerHour = 0.37
+
+// budgetWarningRatio is the share of the monthly budget that triggers a warning
+const budgetWarningRatio = 0.8
+
+// modelPricePerHour holds list prices in USD per audio hour for known speech models
+var modelPricePerHour = map[string]float64{
+	"best": 0.37,
+	"nano": 0.12,
+}
+
+// usageLedger records the estimated spend per month ("2006-01")
+type usageLedger struct {
+	Months map[string]float64 `json:"months"`
+}
+
+// probeDuration returns the duration of a media file using ffprobe
+func probeDuration(mediaFile string) (time.Duration, error) {
+	out, err := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "csv=p=0", mediaFile).Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse duration: %w", err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// estimateCost returns the estimated price of transcribing duration of audio
+func estimateCost(duration time.Duration, model string, pricePerHour float64) float64 {
+	if pricePerHour <= 0 {
+		pricePerHour = defaultPricePerHour
+		if price, ok := modelPricePerHour[model]; ok {
+			pricePerHour = price
+		}
+	}
+	return duration.Hours() * pricePerHour
+}
+
+// costliestModel returns the model of models with the highest price, so that a
+// budget check holds whichever model of a fallback list ends up transcribing. It
+// returns "" (the API default) for no models.
+func costliestModel(models []string) string {
+	costliest := ""
+	for _, model := range models {
+		if costliest == "" || estimateCost(time.Hour, model, 0) > estimateCost(time.Hour, costliest, 0) {
+			costliest = model
+		}
+	}
+	return costliest
+}
+
+// ledgerPath returns the location of the usage ledger
+func ledgerPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "transcribe", "usage.json"), nil
+}
+
+// loadLedger reads the usage ledger, returning an empty one if it does not exist
+func loadLedger() (*usageLedger, error) {
+	ledger := &usageLedger{Months: make(map[string]float64)}
+
+	path, err := ledgerPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	if err := json.Unmarshal(data, ledger); err != nil {
+		return nil, fmt.Errorf("failed to parse usage ledger: %w", err)
+	}
+	if ledger.Months == nil {
+		ledger.Months = make(map[string]float64)
+	}
+	return ledger, nil
+}
+
+// record adds cost to the current month and saves the ledger
+func (l *usageLedger) record(cost float64) error {
+	l.Months[time.Now().Format("2006-01")] += cost
+
+	path, err := ledgerPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// thisMonth returns the spend recorded for the current month
+func (l *usageLedger) thisMonth() float64 {
+	return l.Months[time.Now().Format("2006-01")]
+}
+
+// checkBudget refuses a run whose estimated cost exceeds the per-run ceiling or the
+// remaining monthly budget, and warns when the month passes 80% of its budget.
+// Zero limits are not enforced.
+func checkBudget(cost, maxCost, monthlyBudget float64, ledger *usageLedger) error {
+	if maxCost > 0 && cost > maxCost {
+		return fmt.Errorf("estimated cost $%.2f exceeds --max-cost $%.2f", cost, maxCost)
+	}
+	if monthlyBudget <= 0 {
+		return nil
+	}
+
+	spent := ledger.thisMonth()
+	if spent+cost > monthlyBudget {
+		return fmt.Errorf("estimated cost $%.2f would exceed the monthly budget ($%.2f of $%.2f spent)", cost, spent, monthlyBudget)
+	}
+	if spent+cost >= monthlyBudget*budgetWarningRatio {
+		warnf("this run brings monthly spend to $%.2f of the $%.2f budget", spent+cost, monthlyBudget)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// datasetSampleRate is the sample rate of the clips, as in LJSpeech
+const datasetSampleRate = 22050
+
+// exportDataset cuts every segment of audioFile into a mono WAV clip under
+// dir/wavs and lists the clips with their text and speaker in dir/metadata.csv,
+// in the pipe-separated audio_file|text|speaker_name layout read by Coqui TTS.
+// It returns the path of metadata.csv.
+func exportDataset(dir, audioFile string, transcription *TranscriptionResponse) (string, error) {
+	if len(transcription.Utterances) == 0 {
+		return "", fmt.Errorf("transcript has no segments to export")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "wavs"), 0755); err != nil {
+		return "", fmt.Errorf("failed to create dataset directory: %w", err)
+	}
+
+	prefix := filepath.Base(dir)
+	var metadata strings.Builder
+	metadata.WriteString("audio_file|text|speaker_name\n")
+	for i, utterance := range transcription.Utterances {
+		// Pipes and newlines would break the row
+		text := strings.Join(strings.Fields(strings.ReplaceAll(utterance.Text, "|", " ")), " ")
+		if text == "" || utterance.End <= utterance.Start {
+			continue
+		}
+
+		clip := filepath.Join("wavs", fmt.Sprintf("%s-%04d.wav", prefix, i+1))
+		if err := runFFmpeg(
+			"-ss", fmt.Sprintf("%.3f", float64(utterance.Start)/1000.0),
+			"-t", fmt.Sprintf("%.3f", float64(utterance.End-utterance.Start)/1000.0),
+			"-i", audioFile, "-vn", "-ac", "1", "-ar", fmt.Sprint(datasetSampleRate), "-c:a", "pcm_s16le",
+			filepath.Join(dir, clip), "-y"); err != nil {
+			return "", err
+		}
+
+		speaker := utterance.Speaker
+		if speaker == "" {
+			speaker = "unknown"
+		}
+		metadata.WriteString(fmt.Sprintf("%s|%s|%s\n", filepath.ToSlash(clip), text, speaker))
+	}
+
+	metadataFile := filepath.Join(dir, "metadata.csv")
+	if err := os.WriteFile(metadataFile, []byte(metadata.String()), 0644); err != nil {
+		return "", err
+	}
+	return metadataFile, nil
+}
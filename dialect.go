@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"transcribe/pkg/transcribe"
+)
+
+// dialect is a regional variety of a language with the settings that help the
+// API recognize it
+type dialect struct {
+	// Language is the language code sent to the API unless --language is given
+	Language string
+	// Model is the speech model tried first unless --model-fallback is given; the
+	// larger models cope best with accents
+	Model string
+	// Vocabulary lists words of the dialect that the model is unlikely to expect;
+	// they are boosted like --prompt terms
+	Vocabulary []string
+	// Glossary maps names to the misspellings replaced by them after
+	// transcription, as by a terminology memory; the names are boosted too
+	Glossary map[string][]string
+}
+
+// dialects are the values of --dialect
+var dialects = map[string]dialect{
+	"tr-cypriot": {
+		Language:   "tr",
+		Model:      "best",
+		Vocabulary: []string{"hellim", "pilavuna", "molehiya", "kolokas", "şeftali kebabı"},
+		Glossary: map[string][]string{
+			"Lefkoşa":    {"Lefkosa"},
+			"Gazimağusa": {"Gazimagusa", "Gazi Magusa"},
+			"Güzelyurt":  {"Guzelyurt"},
+			"Girne":      {},
+			"KKTC":       {"K.K.T.C."},
+		},
+	},
+	"en-scottish": {
+		Language:   "en_uk",
+		Model:      "best",
+		Vocabulary: []string{"wee", "aye", "ken", "bairn", "dinnae", "cannae", "wouldnae", "outwith", "loch", "glen", "kirk"},
+		Glossary: map[string][]string{
+			"Edinburgh": {"Edinborough", "Edinborow"},
+			"Glasgow":   {"Glasgo"},
+			"Aberdeen":  {},
+			"Inverness": {"Invernes"},
+			"Holyrood":  {"Holy Rood"},
+		},
+	},
+	"en-irish": {
+		Language:   "en_uk",
+		Model:      "best",
+		Vocabulary: []string{"craic", "grand", "yoke", "eejit", "giving out"},
+		Glossary: map[string][]string{
+			"Taoiseach": {"Tee Shock", "Teeshock"},
+			"Dáil":      {"Dail"},
+			"Gardaí":    {"Gardai"},
+			"Galway":    {},
+		},
+	},
+	"en-indian": {
+		Language:   "en",
+		Model:      "best",
+		Vocabulary: []string{"lakh", "crore", "prepone", "timepass", "do the needful"},
+		Glossary: map[string][]string{
+			"Bengaluru": {"Bengalooru"},
+			"Mumbai":    {"Mumbay"},
+			"Kolkata":   {"Kolkatta"},
+			"Hyderabad": {},
+		},
+	},
+	"en-australian": {
+		Language:   "en_au",
+		Model:      "best",
+		Vocabulary: []string{"arvo", "servo", "ute", "brekkie", "reckon"},
+		Glossary: map[string][]string{
+			"Melbourne": {"Melbun"},
+			"Canberra":  {"Canbra"},
+			"Brisbane":  {},
+		},
+	},
+}
+
+// dialectUsage is the usage of the --dialect flag
+func dialectUsage() string {
+	return "regional `dialect` that selects the language, model, vocabulary and spellings and writes a review of low-confidence segments: " +
+		strings.Join(slices.Sorted(maps.Keys(dialects)), ", ")
+}
+
+// terms returns the vocabulary boosted for the dialect
+func (d dialect) terms() []string {
+	return append(slices.Clone(d.Vocabulary), slices.Sorted(maps.Keys(d.Glossary))...)
+}
+
+// applyGlossary enforces the spellings of the glossary and reports the changes
+func (d dialect) applyGlossary(transcription *TranscriptionResponse) {
+	memory := &terminologyMemory{Terms: slices.Sorted(maps.Keys(d.Glossary)), Variants: d.Glossary}
+	for _, change := range memory.apply(transcription) {
+		fmt.Fprintf(status, "Dialect glossary: %q -> %q (%d times)\n", change.From, change.To, change.Count)
+	}
+}
+
+const (
+	// lowConfidence is the confidence below which a segment may be misheard
+	lowConfidence = 0.6
+	// speakerConfidenceDrop marks segments this far below the speaker's median
+	// confidence as low, for speakers the model hears poorly throughout
+	speakerConfidenceDrop = 0.15
+	// veryLowConfidence is the confidence at which a single segment is reported
+	veryLowConfidence = 0.4
+	// confidenceClusterGap is the longest gap between the low segments of a
+	// speaker that are reported together (ms)
+	confidenceClusterGap = 30000
+)
+
+// confidenceCluster is a run of low-confidence segments of one speaker
+type confidenceCluster struct {
+	Speaker    string
+	Segments   []Utterance
+	Confidence float64
+}
+
+// lowConfidenceClusters returns the runs of low-confidence segments, where an
+// accent most likely made the model mishear. A run needs two segments, or one of
+// very low confidence. Segments without a confidence are ignored.
+func lowConfidenceClusters(utterances []Utterance) []confidenceCluster {
+	bySpeaker := make(map[string][]float64)
+	for _, utterance := range utterances {
+		if utterance.Confidence > 0 {
+			bySpeaker[utterance.Speaker] = append(bySpeaker[utterance.Speaker], utterance.Confidence)
+		}
+	}
+	medians := make(map[string]float64)
+	for speaker, confidences := range bySpeaker {
+		slices.Sort(confidences)
+		medians[speaker] = confidences[len(confidences)/2]
+	}
+	isLow := func(utterance Utterance) bool {
+		return utterance.Confidence > 0 &&
+			(utterance.Confidence < lowConfidence || utterance.Confidence < medians[utterance.Speaker]-speakerConfidenceDrop)
+	}
+
+	var clusters []confidenceCluster
+	open := make(map[string]int) // index of the speaker's latest cluster
+	for _, utterance := range utterances {
+		if !isLow(utterance) {
+			continue
+		}
+		if i, ok := open[utterance.Speaker]; ok {
+			segments := clusters[i].Segments
+			if utterance.Start-segments[len(segments)-1].End <= confidenceClusterGap {
+				clusters[i].Segments = append(segments, utterance)
+				continue
+			}
+		}
+		open[utterance.Speaker] = len(clusters)
+		clusters = append(clusters, confidenceCluster{Speaker: utterance.Speaker, Segments: []Utterance{utterance}})
+	}
+
+	var reported []confidenceCluster
+	for _, cluster := range clusters {
+		sum := 0.0
+		for _, segment := range cluster.Segments {
+			sum += segment.Confidence
+		}
+		cluster.Confidence = sum / float64(len(cluster.Segments))
+		if len(cluster.Segments) >= 2 || cluster.Confidence < veryLowConfidence {
+			reported = append(reported, cluster)
+		}
+	}
+	return reported
+}
+
+// saveReview writes the low-confidence clusters as a review list for the dialect
+func saveReview(filename, name string, clusters []confidenceCluster) error {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Segments likely misheard (%s): %d\n", name, len(clusters)))
+	for _, cluster := range clusters {
+		first, last := cluster.Segments[0], cluster.Segments[len(cluster.Segments)-1]
+		output.WriteString(fmt.Sprintf("\n[%s - %s] Speaker %s, %d segments, confidence %.2f\n",
+			transcribe.FormatTimestamp(float64(first.Start)/1000.0), transcribe.FormatTimestamp(float64(last.End)/1000.0),
+			cluster.Speaker, len(cluster.Segments), cluster.Confidence))
+		for _, segment := range cluster.Segments {
+			output.WriteString(fmt.Sprintf("  [%s] (%.2f) %s\n",
+				transcribe.FormatTimestamp(float64(segment.Start)/1000.0), segment.Confidence, strings.TrimSpace(segment.Text)))
+		}
+	}
+	return os.WriteFile(filename, []byte(output.String()), 0644)
+}
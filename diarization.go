@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// shortSegmentMs is the length below which a segment counts as suspiciously short
+	shortSegmentMs = 1000
+	// unreliableDiarizationScore is the score below which a run is flagged as unreliable
+	unreliableDiarizationScore = 60
+)
+
+// diarizationQuality summarizes how trustworthy the speaker labels of a run look
+type diarizationQuality struct {
+	Score    int
+	Warnings []string
+}
+
+// assessDiarization scores speaker labels from 0 to 100 using heuristics that are
+// typical for failed diarization: speakers alternating very quickly, many segments
+// under a second, and a speaker count that does not match the hint (0 if unknown)
+func assessDiarization(transcription *TranscriptionResponse, expectedSpeakers int) diarizationQuality {
+	var quality diarizationQuality
+	utterances := transcription.Utterances
+	if len(utterances) == 0 {
+		return diarizationQuality{Score: 0, Warnings: []string{"no speaker segments were returned"}}
+	}
+
+	speakers := make(map[string]bool)
+	short, changes := 0, 0
+	for i, utterance := range utterances {
+		speakers[utterance.Speaker] = true
+		if utterance.End-utterance.Start < shortSegmentMs {
+			short++
+		}
+		if i > 0 && utterance.Speaker != utterances[i-1].Speaker {
+			changes++
+		}
+	}
+
+	minutes := math.Max(float64(utterances[len(utterances)-1].End-utterances[0].Start)/60000, 1)
+	changesPerMinute := float64(changes) / minutes
+	shortFraction := float64(short) / float64(len(utterances))
+
+	penalty := 40 * shortFraction
+	if shortFraction > 0.15 {
+		quality.Warnings = append(quality.Warnings, fmt.Sprintf("%.0f%% of segments are shorter than 1s", shortFraction*100))
+	}
+
+	penalty += math.Min(30, math.Max(0, changesPerMinute-6)*3)
+	if changesPerMinute > 10 {
+		quality.Warnings = append(quality.Warnings, fmt.Sprintf("speakers alternate %.1f times per minute", changesPerMinute))
+	}
+
+	if expectedSpeakers > 0 && len(speakers) != expectedSpeakers {
+		diff := math.Abs(float64(len(speakers) - expectedSpeakers))
+		penalty += math.Min(30, diff*15)
+		quality.Warnings = append(quality.Warnings, fmt.Sprintf("detected %d speakers but %d were expected", len(speakers), expectedSpeakers))
+	}
+
+	quality.Score = int(math.Round(math.Max(0, 100-penalty)))
+	return quality
+}
+
+// reportDiarization prints the quality score and any warnings for the run
+func reportDiarization(quality diarizationQuality, expectedSpeakers int) {
+	fmt.Fprintf(status, "Diarization quality: %d/100\n", quality.Score)
+	for _, warning := range quality.Warnings {
+		warnf("%s", warning)
+	}
+	if quality.Score < unreliableDiarizationScore {
+		warnf("speaker labels look unreliable")
+		if expectedSpeakers == 0 {
+			fmt.Fprintln(status, "  Pass --speakers with the number of participants to guide diarization")
+		}
+		fmt.Fprintln(status, "  Recording each participant on a separate channel gives the most reliable speaker labels")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// doctorTool is an external program the CLI runs
+type doctorTool struct {
+	Name     string
+	Args     []string
+	Required bool
+	// Needed tells what the tool is used for
+	Needed string
+}
+
+// doctorTools lists the external programs, the required ones first
+var doctorTools = []doctorTool{
+	{Name: "ffmpeg", Args: []string{"-version"}, Required: true, Needed: "converting media"},
+	{Name: "ffprobe", Args: []string{"-version"}, Required: true, Needed: "probing durations, channels and titles"},
+	{Name: "minisign", Args: []string{"-v"}, Needed: "--sign and verify"},
+	{Name: "demucs", Args: []string{"--help"}, Needed: "--isolate-voice"},
+	{Name: "yt-dlp", Args: []string{"--version"}, Needed: "resolving media on web pages for URL inputs"},
+	{Name: "huggingface-cli", Args: []string{"version"}, Needed: "publish --to hf-dataset"},
+}
+
+// runDoctor implements the doctor subcommand: it checks the external programs,
+// the API key and the configuration directory, and fails if anything required
+// for a transcription run is missing
+func runDoctor(args []string) error {
+	problems := 0
+	report := func(ok bool, required bool, format string, args ...any) {
+		mark := "ok"
+		if !ok {
+			mark = "missing"
+			if required {
+				mark = "FAIL"
+				problems++
+			}
+		}
+		fmt.Printf("[%s] %s\n", mark, fmt.Sprintf(format, args...))
+	}
+
+	for _, tool := range doctorTools {
+		path, err := exec.LookPath(tool.Name)
+		if err != nil {
+			report(false, tool.Required, "%s, needed for %s", tool.Name, tool.Needed)
+			continue
+		}
+		version := ""
+		if out, err := exec.Command(path, tool.Args...).Output(); err == nil {
+			version, _, _ = strings.Cut(strings.TrimSpace(string(out)), "\n")
+		}
+		report(true, tool.Required, "%s: %s %s", tool.Name, path, version)
+	}
+
+	_, source, err := resolveAPIKey("")
+	if err == nil {
+		source = " from " + source
+	}
+	report(err == nil, true, "AssemblyAI API key%s%s", source, errorSuffix(err))
+
+	endpoint := " " + apiBaseURL()
+	if err = checkAPIURL(); err != nil {
+		endpoint = ""
+	}
+	report(err == nil, true, "AssemblyAI API endpoint%s%s", endpoint, errorSuffix(err))
+
+	// Only look at the directory, which is created when it is first written to;
+	// the checks change nothing
+	dir, err := os.UserConfigDir()
+	if err == nil {
+		dir = filepath.Join(dir, "transcribe")
+		var info os.FileInfo
+		if info, err = os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+			err = errors.New("does not exist yet")
+		} else if err == nil && !info.IsDir() {
+			err = errors.New("is not a directory")
+		}
+	}
+	report(err == nil, false, "configuration directory %s%s", dir, errorSuffix(err))
+
+	if problems > 0 {
+		return fmt.Errorf("%d required checks failed", problems)
+	}
+	return nil
+}
+
+// errorSuffix formats err for the end of a report line, or returns "" for nil
+func errorSuffix(err error) string {
+	if err == nil {
+		return ""
+	}
+	return ": " + err.Error()
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"transcribe/pkg/transcribe"
+)
+
+// downloadRetries is how many times a download that stops making progress is
+// resumed before moving on to the next source
+const downloadRetries = 5
+
+// errNotMedia is returned for a source that serves a web page instead of media,
+// such as a login or region-restriction notice
+var errNotMedia = errors.New("source serves a web page, not media")
+
+// errSourceRefused is returned for a source that refuses the request, which
+// retrying does not fix
+var errSourceRefused = errors.New("source refused the request")
+
+// mediaExtensions maps common media types to the extension used in output names,
+// since the system MIME tables may list several for each
+var mediaExtensions = map[string]string{
+	"audio/mpeg":       ".mp3",
+	"audio/mp4":        ".m4a",
+	"audio/aac":        ".aac",
+	"audio/flac":       ".flac",
+	"audio/ogg":        ".ogg",
+	"application/ogg":  ".ogg",
+	"audio/wav":        ".wav",
+	"audio/wave":       ".wav",
+	"audio/x-wav":      ".wav",
+	"audio/webm":       ".webm",
+	"video/webm":       ".webm",
+	"video/mp4":        ".mp4",
+	"video/quicktime":  ".mov",
+	"video/x-matroska": ".mkv",
+}
+
+// isURL reports whether arg is an http or https URL
+func isURL(arg string) bool {
+	return strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://")
+}
+
+// downloadInput downloads the media from the first of sources that works and
+// returns the local temp file and the file name to base the outputs on. Each
+// source is resumed where it stopped when the connection drops. A source that
+// serves a web page is handed to yt-dlp, when it is installed, to find the media
+// on the page.
+func downloadInput(sources []string) (string, string, error) {
+	var errs []error
+	for i, source := range sources {
+		if i > 0 {
+			fmt.Fprintf(status, "Trying mirror %s...\n", source)
+		}
+		file, contentType, err := downloadFrom(source)
+		if errors.Is(err, errNotMedia) {
+			if resolved, rerr := resolveMediaURL(source); rerr == nil {
+				fmt.Fprintf(status, "Resolved media URL: %s\n", resolved)
+				file, contentType, err = downloadFrom(resolved)
+			}
+		}
+		if err == nil {
+			return file, downloadName(source, contentType), nil
+		}
+		warnf("download from %s failed: %v", source, err)
+		errs = append(errs, err)
+	}
+	return "", "", fmt.Errorf("all sources failed: %w", errors.Join(errs...))
+}
+
+// downloadFrom downloads source into a temp file, resuming with range requests
+// after failures, and returns the file with the media type of the content
+func downloadFrom(source string) (string, string, error) {
+	tmpFile, err := os.CreateTemp("", "transcribe-download-*")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	cleanup := func() {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+	}
+
+	var written, size int64 = 0, -1
+	var contentType string
+	var modified time.Time
+	lastProgress := time.Now()
+	for attempt := 0; ; {
+		n, err := func() (int64, error) {
+			p := newProgress()
+			ctx, stop := watchContext(p)
+			defer stop()
+			req, err := http.NewRequestWithContext(ctx, "GET", source, nil)
+			if err != nil {
+				return 0, fmt.Errorf("failed to create request: %w", err)
+			}
+			if written > 0 {
+				req.Header.Set("Range", fmt.Sprintf("bytes=%d-", written))
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				if errors.Is(context.Cause(ctx), transcribe.ErrStalled) {
+					return 0, stallError()
+				}
+				return 0, err
+			}
+			defer resp.Body.Close()
+
+			switch {
+			case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && written > 0:
+				// Everything was received before the connection dropped
+				return 0, io.EOF
+			case resp.StatusCode == http.StatusOK && written > 0:
+				// The server ignored the range; start over
+				if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+					return 0, err
+				}
+				if err := tmpFile.Truncate(0); err != nil {
+					return 0, err
+				}
+				written = 0
+			case resp.StatusCode >= 400 && resp.StatusCode < 500:
+				return 0, fmt.Errorf("%w with %s", errSourceRefused, resp.Status)
+			case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent:
+				return 0, fmt.Errorf("download failed with %s", resp.Status)
+			}
+
+			body := bufio.NewReader(resp.Body)
+			if written == 0 {
+				if contentType, err = sniffMedia(body, resp.Header.Get("Content-Type")); err != nil {
+					return 0, err
+				}
+				if resp.ContentLength >= 0 {
+					size = resp.ContentLength
+				}
+				modified, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+			}
+
+			var n int64
+			buf := make([]byte, 1<<20)
+			for {
+				m, err := body.Read(buf)
+				if errors.Is(context.Cause(ctx), transcribe.ErrStalled) {
+					err = stallError()
+				}
+				if m > 0 {
+					p.touch()
+					if _, werr := tmpFile.Write(buf[:m]); werr != nil {
+						return n, werr
+					}
+					n += int64(m)
+					if size > 0 && time.Since(lastProgress) >= copyProgressInterval {
+						fmt.Fprintf(status, "Downloading: %.0f%%\n", 100*float64(written+n)/float64(size))
+						lastProgress = time.Now()
+					}
+				}
+				if err != nil {
+					return n, err
+				}
+			}
+		}()
+		written += n
+		if errors.Is(err, io.EOF) && (size < 0 || written >= size) {
+			break
+		}
+		if err == nil || errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		if errors.Is(err, errNotMedia) || errors.Is(err, errSourceRefused) {
+			cleanup()
+			return "", "", err
+		}
+		if n == 0 {
+			attempt++
+		} else {
+			attempt = 0
+		}
+		if attempt >= downloadRetries {
+			cleanup()
+			return "", "", err
+		}
+		warnf("download interrupted at %s, resuming: %v", formatBytes(written), err)
+		time.Sleep(time.Duration(attempt+1) * time.Second)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", "", err
+	}
+	// Date the recording by the server's modification time, as for local files
+	if !modified.IsZero() {
+		os.Chtimes(tmpFile.Name(), modified, modified)
+	}
+	return tmpFile.Name(), contentType, nil
+}
+
+// sniffMedia checks the start of the content and returns its media type: the
+// declared type unless it is generic, otherwise the sniffed one. Content that
+// sniffs as text is rejected with errNotMedia.
+func sniffMedia(body *bufio.Reader, declared string) (string, error) {
+	head, _ := body.Peek(512)
+	sniffed := http.DetectContentType(head)
+	if strings.HasPrefix(sniffed, "text/") {
+		return "", errNotMedia
+	}
+	declared, _, _ = mime.ParseMediaType(declared)
+	if declared == "" || declared == "application/octet-stream" || declared == "binary/octet-stream" {
+		return sniffed, nil
+	}
+	return declared, nil
+}
+
+// resolveMediaURL asks yt-dlp for the direct URL of the best audio on a web page
+func resolveMediaURL(page string) (string, error) {
+	out, err := exec.Command("yt-dlp", "--get-url", "--format", "bestaudio/best", page).Output()
+	if err != nil {
+		return "", fmt.Errorf("yt-dlp failed: %w", err)
+	}
+	resolved, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	if !isURL(resolved) {
+		return "", fmt.Errorf("yt-dlp found no media URL")
+	}
+	return resolved, nil
+}
+
+// downloadName returns the file name for a downloaded source: the last element
+// of the URL path, with an extension for contentType if it has none
+func downloadName(source, contentType string) string {
+	name := "download"
+	if u, err := url.Parse(source); err == nil {
+		if base := path.Base(u.Path); base != "/" && base != "." {
+			name = base
+		}
+	}
+	name = filepath.Base(filepath.FromSlash(name))
+	if filepath.Ext(name) == "" {
+		if extension, ok := mediaExtensions[contentType]; ok {
+			name += extension
+		} else if extensions, _ := mime.ExtensionsByType(contentType); len(extensions) > 0 {
+			name += extensions[0]
+		}
+	}
+	return name
+}
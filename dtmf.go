@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"transcribe/pkg/transcribe"
+)
+
+const (
+	// dtmfSampleRate is the rate audio is decoded at for tone detection; all DTMF
+	// frequencies are below its Nyquist frequency
+	dtmfSampleRate = 8000
+	// dtmfFrameSize is the standard Goertzel block of 205 samples (25.6 ms), whose
+	// bins fall close to the eight DTMF frequencies
+	dtmfFrameSize = 205
+	// dtmfMinFrames is the number of consecutive tone frames that make a key press;
+	// keys last at least 40 ms
+	dtmfMinFrames = 2
+	// dtmfMergeGap joins key presses into one masked region when they are this close,
+	// so that the pauses between the digits of a card number are masked too
+	dtmfMergeGap = 2 * time.Second
+	// dtmfPadding widens each masked region on both sides
+	dtmfPadding = 250 * time.Millisecond
+	// dtmfRedaction replaces the text of the utterances over a masked region
+	dtmfRedaction = "[DTMF]"
+)
+
+// dtmfRows and dtmfColumns are the low and high frequency groups of the keypad
+var (
+	dtmfRows    = []float64{697, 770, 852, 941}
+	dtmfColumns = []float64{1209, 1336, 1477, 1633}
+)
+
+// detectDTMF decodes audioFile and returns the regions holding keypad tones, merged
+// and padded for masking
+func detectDTMF(audioFile string) ([]timeRange, error) {
+	cmd := exec.Command("ffmpeg", "-i", audioFile, "-vn", "-ac", "1", "-ar", fmt.Sprint(dtmfSampleRate), "-f", "s16le", "-")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	var tones []timeRange
+	reader := bufio.NewReader(stdout)
+	block := make([]int16, dtmfFrameSize)
+	frame := make([]float64, dtmfFrameSize)
+	run := 0
+	for n := 0; ; n++ {
+		err := binary.Read(reader, binary.LittleEndian, block)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			cmd.Wait()
+			return nil, fmt.Errorf("failed to read audio: %w", err)
+		}
+
+		for i, sample := range block {
+			frame[i] = float64(sample) / 32768
+		}
+		if !isDTMFFrame(frame) {
+			run = 0
+			continue
+		}
+		run++
+		if run < dtmfMinFrames {
+			continue
+		}
+		start := dtmfFrameTime(n - run + 1)
+		end := dtmfFrameTime(n + 1)
+		if run > dtmfMinFrames {
+			// Extend the key press found on an earlier frame
+			tones[len(tones)-1].End = end
+		} else {
+			tones = append(tones, timeRange{Start: start, End: end})
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w", err)
+	}
+	return mergeDTMF(tones), nil
+}
+
+// dtmfFrameTime returns the start time of frame n
+func dtmfFrameTime(n int) time.Duration {
+	return time.Duration(n) * dtmfFrameSize * time.Second / dtmfSampleRate
+}
+
+// isDTMFFrame reports whether a frame is a keypad tone: one row and one column
+// frequency that together carry most of its energy, each standing clear of the
+// other frequencies of its group
+func isDTMFFrame(frame []float64) bool {
+	energy := 0.0
+	for _, x := range frame {
+		energy += x * x
+	}
+	if math.Sqrt(energy/float64(len(frame))) < 0.01 {
+		return false
+	}
+
+	// The share of the frame energy at each frequency; a pure tone has about 1
+	share := func(frequency float64) float64 {
+		return 2 * goertzel(frame, frequency) / (float64(len(frame)) * energy)
+	}
+	strongest := func(frequencies []float64) (best, second float64) {
+		for _, frequency := range frequencies {
+			p := share(frequency)
+			if p > best {
+				best, second = p, best
+			} else if p > second {
+				second = p
+			}
+		}
+		return best, second
+	}
+	row, rowSecond := strongest(dtmfRows)
+	column, columnSecond := strongest(dtmfColumns)
+	return row+column >= 0.6 && row >= 0.15 && column >= 0.15 &&
+		rowSecond < row/4 && columnSecond < column/4
+}
+
+// goertzel returns the squared magnitude of the frame at frequency
+func goertzel(frame []float64, frequency float64) float64 {
+	coefficient := 2 * math.Cos(2*math.Pi*frequency/dtmfSampleRate)
+	var s1, s2 float64
+	for _, x := range frame {
+		s1, s2 = x+coefficient*s1-s2, s1
+	}
+	return s1*s1 + s2*s2 - coefficient*s1*s2
+}
+
+// mergeDTMF joins key presses closer than dtmfMergeGap and pads the regions
+func mergeDTMF(tones []timeRange) []timeRange {
+	var regions []timeRange
+	for _, tone := range tones {
+		tone.Start = max(tone.Start-dtmfPadding, 0)
+		tone.End += dtmfPadding
+		if len(regions) > 0 && tone.Start-regions[len(regions)-1].End <= dtmfMergeGap {
+			regions[len(regions)-1].End = tone.End
+			continue
+		}
+		regions = append(regions, tone)
+	}
+	return regions
+}
+
+// maskAudio writes a temporary MP3 of audioFile with the regions silenced
+func maskAudio(audioFile string, regions []timeRange) (string, error) {
+	var between []string
+	for _, region := range regions {
+		between = append(between, fmt.Sprintf("between(t,%.3f,%.3f)", region.Start.Seconds(), region.End.Seconds()))
+	}
+
+	tmpFile, err := os.CreateTemp("", "transcribe-*.mp3")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpFile.Close()
+
+	filter := fmt.Sprintf("volume=enable='%s':volume=0", strings.Join(between, "+"))
+	if err := runFFmpeg("-i", audioFile, "-af", filter, "-acodec", "libmp3lame", "-q:a", "2", tmpFile.Name(), "-y"); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	return tmpFile.Name(), nil
+}
+
+// redactDTMF replaces the text of the utterances that overlap a masked region,
+// where a caller may read out the digits they key, and returns their number
+func redactDTMF(transcription *TranscriptionResponse, regions []timeRange) int {
+	count := 0
+	for i, utterance := range transcription.Utterances {
+		start := time.Duration(utterance.Start) * time.Millisecond
+		end := time.Duration(utterance.End) * time.Millisecond
+		for _, region := range regions {
+			if start < region.End && end > region.Start {
+				transcription.Utterances[i].Text = dtmfRedaction
+				count++
+				break
+			}
+		}
+	}
+	if count > 0 {
+		transcription.Text = transcribe.TranscriptText(transcription)
+	}
+	return count
+}
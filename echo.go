@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	// envelopeRate is the number of loudness envelope points per second of audio
+	envelopeRate = 100
+	// maxEchoLagBlocks is the largest delay between duplicated channels considered
+	maxEchoLagBlocks = envelopeRate
+	// minEchoLagBlocks is the smallest delay counted as double capture rather than a
+	// plain mono recording stored as stereo
+	minEchoLagBlocks = 3
+	// echoCorrelation is the envelope correlation above which channels are duplicates
+	echoCorrelation = 0.85
+)
+
+// probeChannels returns the number of channels of the first audio stream
+func probeChannels(mediaFile string) (int, error) {
+	out, err := exec.Command("ffprobe", "-v", "error", "-select_streams", "a:0", "-show_entries", "stream=channels", "-of", "csv=p=0", mediaFile).Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	channels, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse channel count: %w", err)
+	}
+	return channels, nil
+}
+
+// readEnvelopes decodes the first two channels of mediaFile and returns their RMS
+// loudness envelopes
+func readEnvelopes(mediaFile string) ([2][]float64, error) {
+	var envelopes [2][]float64
+	cmd := exec.Command("ffmpeg", "-i", mediaFile, "-vn", "-af", "pan=stereo|c0=c0|c1=c1", "-ar", "8000", "-f", "s16le", "-")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return envelopes, err
+	}
+	if err := cmd.Start(); err != nil {
+		return envelopes, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	reader := bufio.NewReader(stdout)
+	block := make([]int16, 2*8000/envelopeRate)
+	for {
+		err := binary.Read(reader, binary.LittleEndian, block)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			cmd.Wait()
+			return envelopes, fmt.Errorf("failed to read audio: %w", err)
+		}
+
+		var sums [2]float64
+		for i, sample := range block {
+			sums[i%2] += float64(sample) * float64(sample)
+		}
+		for c := range envelopes {
+			envelopes[c] = append(envelopes[c], math.Sqrt(sums[c]/float64(len(block)/2)))
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return envelopes, fmt.Errorf("ffmpeg failed: %w", err)
+	}
+	return envelopes, nil
+}
+
+// normalize returns x scaled to zero mean and unit variance
+func normalize(x []float64) []float64 {
+	var mean float64
+	for _, v := range x {
+		mean += v
+	}
+	mean /= float64(len(x))
+
+	var variance float64
+	for _, v := range x {
+		variance += (v - mean) * (v - mean)
+	}
+	std := math.Sqrt(variance / float64(len(x)))
+
+	out := make([]float64, len(x))
+	for i, v := range x {
+		if std > 0 {
+			out[i] = (v - mean) / std
+		}
+	}
+	return out
+}
+
+// bestLag returns the lag of b relative to a (in envelope blocks) with the highest
+// correlation, and that correlation
+func bestLag(a, b []float64, maxLag int) (int, float64) {
+	a, b = normalize(a), normalize(b)
+	bestLag, best := 0, math.Inf(-1)
+	for lag := -maxLag; lag <= maxLag; lag++ {
+		var sum float64
+		n := 0
+		for i := max(0, -lag); i < len(a) && i+lag < len(b); i++ {
+			sum += a[i] * b[i+lag]
+			n++
+		}
+		if n > 0 && sum/float64(n) > best {
+			bestLag, best = lag, sum/float64(n)
+		}
+	}
+	return bestLag, best
+}
+
+// detectDoubleCapture checks whether the two channels of mediaFile carry the same
+// audio with a delay, as happens when a call is recorded on both ends. It returns
+// the index of the leading channel, or -1 if no double capture was found.
+func detectDoubleCapture(mediaFile string) (int, error) {
+	channels, err := probeChannels(mediaFile)
+	if err != nil {
+		return -1, err
+	}
+	if channels < 2 {
+		return -1, nil
+	}
+
+	envelopes, err := readEnvelopes(mediaFile)
+	if err != nil {
+		return -1, err
+	}
+	if len(envelopes[0]) <= 2*maxEchoLagBlocks {
+		return -1, nil
+	}
+
+	lag, correlation := bestLag(envelopes[0], envelopes[1], maxEchoLagBlocks)
+	if correlation < echoCorrelation || (lag < minEchoLagBlocks && lag > -minEchoLagBlocks) {
+		return -1, nil
+	}
+
+	fmt.Fprintf(status, "Detected duplicated audio: channels correlate at %.2f with a %dms delay\n", correlation, abs(lag)*1000/envelopeRate)
+	// A positive lag means channel 1 trails channel 0
+	if lag > 0 {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"transcribe/pkg/transcribe"
+)
+
+// enclosureTypes maps the media extensions looked for next to a transcript to
+// the type announced in the feed enclosure
+var enclosureTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".flac": "audio/flac",
+	".wav":  "audio/wav",
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".mkv":  "video/x-matroska",
+}
+
+// feedItem is an episode of the generated feed
+type feedItem struct {
+	Title       string
+	GUID        string
+	Published   time.Time
+	Media       string
+	MediaType   string
+	MediaSize   int64
+	Transcripts [][2]string // file and type
+	Chapters    string
+}
+
+// podcastTranscript is the JSON transcript format of the podcast namespace
+type podcastTranscript struct {
+	Version  string                  `json:"version"`
+	Segments []podcastTranscriptLine `json:"segments"`
+}
+
+type podcastTranscriptLine struct {
+	Speaker   string  `json:"speaker,omitempty"`
+	StartTime float64 `json:"startTime"`
+	EndTime   float64 `json:"endTime"`
+	Body      string  `json:"body"`
+}
+
+// podcastChapters is the JSON chapters format of the podcast namespace
+type podcastChapters struct {
+	Version  string           `json:"version"`
+	Chapters []podcastChapter `json:"chapters"`
+}
+
+type podcastChapter struct {
+	StartTime float64 `json:"startTime"`
+	EndTime   float64 `json:"endTime,omitempty"`
+	Title     string  `json:"title"`
+}
+
+// runFeed implements the feed subcommand. "feed generate" writes an RSS feed with
+// an episode for each transcript JSON whose media sits next to it under the same
+// name. Each episode links the media, a podcast:transcript in the podcast JSON
+// format (plus any SRT and VTT outputs found) and, when the transcript has
+// chapters, podcast:chapters. The JSON files are written next to the transcripts,
+// and all files must be inside the directory of the feed so that they can be
+// served from --base-url.
+func runFeed(args []string) error {
+	if len(args) == 0 || args[0] != "generate" {
+		return errors.New("usage: transcribe feed generate --base-url URL [flags] <transcript.json>...")
+	}
+	fs := flag.NewFlagSet("feed generate", flag.ExitOnError)
+	baseURL := fs.String("base-url", "", "URL that the directory of the feed is served from")
+	title := fs.String("title", "Transcribed archive", "title of the feed")
+	description := fs.String("description", "", "description of the feed")
+	out := fs.String("out", "feed.xml", "write the feed to this `file`")
+	positional := parseArgs(fs, args[1:])
+	if *baseURL == "" || len(positional) == 0 {
+		return errors.New("usage: transcribe feed generate --base-url URL [flags] <transcript.json>...")
+	}
+	if _, err := url.Parse(*baseURL); err != nil {
+		return fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	root, err := filepath.Abs(filepath.Dir(*out))
+	if err != nil {
+		return err
+	}
+	link := func(file string) (string, error) {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return "", fmt.Errorf("%s is outside %s, the directory of the feed", file, root)
+		}
+		escaped := strings.Split(filepath.ToSlash(rel), "/")
+		for i, part := range escaped {
+			escaped[i] = url.PathEscape(part)
+		}
+		return strings.TrimSuffix(*baseURL, "/") + "/" + path.Join(escaped...), nil
+	}
+
+	var items []feedItem
+	for _, transcriptFile := range positional {
+		item, err := feedEpisode(transcriptFile)
+		if err != nil {
+			return err
+		}
+		for _, file := range []*string{&item.Media, &item.Chapters} {
+			if *file != "" {
+				if *file, err = link(*file); err != nil {
+					return err
+				}
+			}
+		}
+		for i := range item.Transcripts {
+			if item.Transcripts[i][0], err = link(item.Transcripts[i][0]); err != nil {
+				return err
+			}
+		}
+		items = append(items, item)
+	}
+	// Newest first, as podcast apps expect
+	slices.SortStableFunc(items, func(a, b feedItem) int { return b.Published.Compare(a.Published) })
+
+	if err := os.WriteFile(*out, []byte(renderFeed(*title, *description, *baseURL, items)), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Feed with %d episodes saved to: %s\n", len(items), *out)
+	return nil
+}
+
+// feedEpisode builds the episode of a transcript, writing its podcast JSON
+// transcript and chapters next to it
+func feedEpisode(transcriptFile string) (feedItem, error) {
+	transcription, err := loadTranscription(transcriptFile)
+	if err != nil {
+		return feedItem{}, err
+	}
+	base := strings.TrimSuffix(transcriptFile, filepath.Ext(transcriptFile))
+	item := feedItem{Title: filepath.Base(base), GUID: transcription.ID}
+
+	for _, extension := range slices.Sorted(maps.Keys(enclosureTypes)) {
+		if info, err := os.Stat(base + extension); err == nil {
+			item.Media, item.MediaType, item.MediaSize = base+extension, enclosureTypes[extension], info.Size()
+			item.Published = info.ModTime()
+			break
+		}
+	}
+	if item.Media == "" {
+		return feedItem{}, fmt.Errorf("no media found next to %s", transcriptFile)
+	}
+	if item.GUID == "" {
+		item.GUID = item.Title
+	}
+
+	doc := podcastTranscript{Version: "1.0.0", Segments: []podcastTranscriptLine{}}
+	for _, utterance := range transcription.Utterances {
+		speaker := ""
+		if utterance.Speaker != "" {
+			speaker = "Speaker " + utterance.Speaker
+		}
+		doc.Segments = append(doc.Segments, podcastTranscriptLine{
+			Speaker:   speaker,
+			StartTime: float64(utterance.Start) / 1000.0,
+			EndTime:   float64(utterance.End) / 1000.0,
+			Body:      strings.TrimSpace(utterance.Text),
+		})
+	}
+	transcriptJSON := base + ".podcast.json"
+	if err := writeFeedJSON(transcriptJSON, doc); err != nil {
+		return feedItem{}, err
+	}
+	item.Transcripts = append(item.Transcripts, [2]string{transcriptJSON, "application/json"})
+	for _, sibling := range [][2]string{{".srt", "application/x-subrip"}, {".vtt", "text/vtt"}} {
+		if _, err := os.Stat(base + sibling[0]); err == nil {
+			item.Transcripts = append(item.Transcripts, [2]string{base + sibling[0], sibling[1]})
+		}
+	}
+
+	if len(transcription.Chapters) > 0 {
+		chapters := podcastChapters{Version: "1.2.0"}
+		for _, chapter := range transcription.Chapters {
+			chapters.Chapters = append(chapters.Chapters, podcastChapter{
+				StartTime: float64(chapter.Start) / 1000.0,
+				EndTime:   float64(chapter.End) / 1000.0,
+				Title:     chapter.Headline,
+			})
+		}
+		item.Chapters = base + ".chapters.json"
+		if err := writeFeedJSON(item.Chapters, chapters); err != nil {
+			return feedItem{}, err
+		}
+	}
+	return item, nil
+}
+
+// writeFeedJSON writes v as indented JSON
+func writeFeedJSON(filename string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// renderFeed formats the episodes as an RSS 2.0 feed with the podcast namespace
+func renderFeed(title, description, link string, items []feedItem) string {
+	var output strings.Builder
+	output.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	output.WriteString(`<rss version="2.0" xmlns:podcast="https://podcastindex.org/namespace/1.0">` + "\n")
+	output.WriteString("  <channel>\n")
+	output.WriteString(fmt.Sprintf("    <title>%s</title>\n", transcribe.XMLEscape(title)))
+	output.WriteString(fmt.Sprintf("    <link>%s</link>\n", transcribe.XMLEscape(link)))
+	output.WriteString(fmt.Sprintf("    <description>%s</description>\n", transcribe.XMLEscape(description)))
+	for _, item := range items {
+		output.WriteString("    <item>\n")
+		output.WriteString(fmt.Sprintf("      <title>%s</title>\n", transcribe.XMLEscape(item.Title)))
+		output.WriteString(fmt.Sprintf("      <guid isPermaLink=\"false\">%s</guid>\n", transcribe.XMLEscape(item.GUID)))
+		output.WriteString(fmt.Sprintf("      <pubDate>%s</pubDate>\n", item.Published.Format(time.RFC1123Z)))
+		output.WriteString(fmt.Sprintf("      <enclosure url=\"%s\" length=\"%d\" type=\"%s\"/>\n", transcribe.XMLEscape(item.Media), item.MediaSize, item.MediaType))
+		for _, transcript := range item.Transcripts {
+			output.WriteString(fmt.Sprintf("      <podcast:transcript url=\"%s\" type=\"%s\"/>\n", transcribe.XMLEscape(transcript[0]), transcript[1]))
+		}
+		if item.Chapters != "" {
+			output.WriteString(fmt.Sprintf("      <podcast:chapters url=\"%s\" type=\"application/json+chapters\"/>\n", transcribe.XMLEscape(item.Chapters)))
+		}
+		output.WriteString("    </item>\n")
+	}
+	output.WriteString("  </channel>\n</rss>\n")
+	return output.String()
+}
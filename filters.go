@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"transcribe/pkg/transcribe"
+)
+
+// stringList is a flag that may be given multiple times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// timeRange is a span of the recording; an End of zero means the end of the recording
+type timeRange struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// contains reports whether the position in milliseconds falls within the range
+func (r timeRange) contains(ms int) bool {
+	position := time.Duration(ms) * time.Millisecond
+	return position >= r.Start && (r.End == 0 || position < r.End)
+}
+
+// parseTimeRange parses ranges like "00:00-02:30" or "01:00:00-" (to the end)
+func parseTimeRange(value string) (timeRange, error) {
+	startText, endText, ok := strings.Cut(value, "-")
+	if !ok {
+		return timeRange{}, fmt.Errorf("invalid time range: %s", value)
+	}
+
+	start, err := parseTimestamp(startText)
+	if err != nil {
+		return timeRange{}, err
+	}
+	var end time.Duration
+	if endText != "" {
+		end, err = parseTimestamp(endText)
+		if err != nil {
+			return timeRange{}, err
+		}
+		if end <= start {
+			return timeRange{}, fmt.Errorf("time range ends before it starts: %s", value)
+		}
+	}
+	return timeRange{Start: start, End: end}, nil
+}
+
+// normalizeSpeaker maps "Speaker C", "C" and "Speaker 3" to the API's label "C"
+func normalizeSpeaker(name string) string {
+	name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "Speaker "))
+	if n, err := strconv.Atoi(name); err == nil && n >= 1 && n <= 26 {
+		return string(rune('A' + n - 1))
+	}
+	return name
+}
+
+// ignoreSegments removes utterances that start in one of the ranges or belong to one
+// of the speakers, returning the number of utterances removed
+func ignoreSegments(transcription *TranscriptionResponse, ranges []timeRange, speakers []string) int {
+	for i := range speakers {
+		speakers[i] = normalizeSpeaker(speakers[i])
+	}
+
+	before := len(transcription.Utterances)
+	transcription.Utterances = slices.DeleteFunc(transcription.Utterances, func(utterance Utterance) bool {
+		if slices.Contains(speakers, utterance.Speaker) {
+			return true
+		}
+		return slices.ContainsFunc(ranges, func(r timeRange) bool { return r.contains(utterance.Start) })
+	})
+
+	removed := before - len(transcription.Utterances)
+	if removed > 0 {
+		transcription.Text = transcribe.TranscriptText(transcription)
+	}
+	return removed
+}
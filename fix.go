@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"transcribe/pkg/transcribe"
+)
+
+// runFix implements the fix subcommand: it re-transcribes the given time ranges and
+// patches the new segments into an existing transcript JSON document
+func runFix(args []string) error {
+	fs := flag.NewFlagSet("fix", flag.ExitOnError)
+	var rangeValues stringList
+	fs.Var(&rangeValues, "range", "time range `HH:MM:SS-HH:MM:SS` to re-transcribe (repeatable)")
+	model := fs.String("model", "", "speech model used for the re-transcription")
+	languageFlag := fs.String("language", "", languageUsage+" (default: the language of the transcript)")
+	maxCost := fs.Float64("max-cost", 0, "refuse to re-transcribe when the estimated cost in USD exceeds this amount")
+	monthlyBudget := fs.Float64("monthly-budget", 0, "monthly budget in USD, checked against the spend recorded in the usage ledger")
+	pricePerHour := fs.Float64("price-per-hour", 0, "price in USD per audio hour used for cost estimates (default: list price of the model)")
+	out := fs.String("out", "", "write the patched transcript here instead of overwriting the input; a .gz name is gzipped")
+	apiKeyFlag := fs.String("api-key", "", apiKeyUsage)
+	fs.StringVar(&apiURL, "api-url", "", apiURLUsage)
+	positional := parseArgs(fs, args)
+	if len(positional) < 1 || len(positional) > 2 || len(rangeValues) == 0 {
+		return errors.New("usage: transcribe fix --range HH:MM:SS-HH:MM:SS [flags] <transcript.json> [audio-file]")
+	}
+
+	var ranges []timeRange
+	for _, value := range rangeValues {
+		r, err := parseTimeRange(value)
+		if err != nil {
+			return err
+		}
+		ranges = append(ranges, r)
+	}
+
+	transcription, err := loadTranscription(positional[0])
+	if err != nil {
+		return err
+	}
+	language, err := parseLanguage(*languageFlag)
+	if err != nil {
+		return err
+	}
+	if language == "" {
+		// Keep the ranges in the language the rest of the transcript was heard in
+		language = transcription.LanguageCode
+	}
+
+	policy, err := enforcePolicy(fs, *model)
+	if err != nil {
+		return err
+	}
+	if err := checkAPIURL(); err != nil {
+		return err
+	}
+	apiKey, err := loadAPIKey(*apiKeyFlag)
+	if err != nil {
+		return err
+	}
+
+	// The ranges are billed like any run: record them in the usage ledger and
+	// refuse them when they exceed a budget
+	enforceBudget := *maxCost > 0 || *monthlyBudget > 0
+	var duration time.Duration
+	for _, r := range ranges {
+		duration += r.End - r.Start
+	}
+	cost := estimateCost(duration, *model, *pricePerHour)
+	fmt.Fprintf(status, "Estimated cost: $%.2f for %s of audio\n", cost, duration.Round(time.Second))
+	ledger, err := loadLedger()
+	if err != nil {
+		if enforceBudget {
+			return err
+		}
+		warnf("failed to load usage ledger, this run is not recorded: %v", err)
+	}
+	if enforceBudget {
+		if err := checkBudget(cost, *maxCost, *monthlyBudget, ledger); err != nil {
+			return err
+		}
+	}
+
+	// Reuse the audio the transcript was made from unless a file is given
+	audioURL := transcription.AudioURL
+	if len(positional) == 2 {
+		fmt.Fprintln(status, "Converting audio to MP3...")
+		mp3File, err := convertToMP3(positional[1], convertOptions{})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if policy.strict() {
+				shredFile(mp3File)
+			} else {
+				os.Remove(mp3File)
+			}
+		}()
+
+		fmt.Fprintln(status, "Uploading audio file...")
+		audioURL, err = uploadAudio(mp3File, apiKey)
+		if err != nil {
+			return err
+		}
+	}
+	if audioURL == "" {
+		return errors.New("transcript has no audio_url, pass the audio file")
+	}
+
+	for _, r := range ranges {
+		fmt.Fprintf(status, "Re-transcribing %s-%s...\n", transcribe.FormatTimestamp(r.Start.Seconds()), transcribe.FormatTimestamp(r.End.Seconds()))
+		request := transcribe.Options{
+			SpeakerLabels:  true,
+			SpeechModel:    *model,
+			LanguageCode:   language,
+			AudioStartFrom: int(r.Start.Milliseconds()),
+			AudioEndAt:     int(r.End.Milliseconds()),
+		}
+		if policy != nil {
+			policy.applyRequest(&request)
+		}
+		patch, err := transcribeAudio(audioURL, request, apiKey)
+		if err != nil {
+			return err
+		}
+		if ledger != nil {
+			if err := ledger.record(estimateCost(r.End-r.Start, *model, *pricePerHour)); err != nil {
+				warnf("failed to update usage ledger: %v", err)
+			}
+		}
+		if policy.strict() {
+			if err := deleteTranscript(patch.ID, apiKey); err != nil {
+				return fmt.Errorf("failed to delete the re-transcription from the API: %w", err)
+			}
+		}
+		reportRepairs(transcribe.Patch(transcription, r.Start, r.End, patch.Utterances))
+	}
+	warnf("speaker labels in re-transcribed ranges are assigned independently and may not match the rest of the transcript")
+
+	data, err := marshalTranscription(transcription)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		*out = positional[0]
+	}
+	// A gzipped raw response stays gzipped, so that it is not overwritten with
+	// plain JSON under a .gz name
+	if strings.HasSuffix(*out, ".gz") {
+		data, err = compressGzip(strings.TrimSuffix(filepath.Base(*out), ".gz"), data)
+		if err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(*out, data, 0644); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(status, "Patched transcript saved to: %s\n", *out)
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"transcribe/pkg/transcribe"
+)
+
+const (
+	// followContextBefore and followContextAfter are the number of segments shown
+	// around the current one
+	followContextBefore = 3
+	followContextAfter  = 8
+)
+
+// runFollow implements the follow subcommand: it plays the audio with ffplay and
+// scrolls the transcript in sync, highlighting the current segment
+func runFollow(args []string) error {
+	fs := flag.NewFlagSet("follow", flag.ExitOnError)
+	positional := parseArgs(fs, args)
+	if len(positional) != 2 {
+		return errors.New("usage: transcribe follow <transcript.json> <audio-file>")
+	}
+
+	transcription, err := loadTranscription(positional[0])
+	if err != nil {
+		return err
+	}
+	if len(transcription.Utterances) == 0 {
+		return errors.New("transcript has no segments to follow")
+	}
+
+	cmd := exec.Command("ffplay", "-nodisp", "-autoexit", "-loglevel", "quiet", positional[1])
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffplay: %w", err)
+	}
+	started := time.Now()
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	current := -1
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				return fmt.Errorf("ffplay failed: %w", err)
+			}
+			return nil
+		case <-ticker.C:
+			index := currentUtterance(transcription.Utterances, int(time.Since(started).Milliseconds()))
+			if index != current {
+				current = index
+				fmt.Print(renderFollowWindow(transcription.Utterances, current))
+			}
+		}
+	}
+}
+
+// currentUtterance returns the index of the last utterance that started at or before
+// the given position in milliseconds
+func currentUtterance(utterances []Utterance, position int) int {
+	index := 0
+	for i, utterance := range utterances {
+		if utterance.Start > position {
+			break
+		}
+		index = i
+	}
+	return index
+}
+
+// renderFollowWindow clears the terminal and draws the segments around current,
+// with the current one in reverse video
+func renderFollowWindow(utterances []Utterance, current int) string {
+	var output strings.Builder
+	output.WriteString("\033[H\033[2J")
+
+	first := max(0, current-followContextBefore)
+	last := min(len(utterances), current+followContextAfter+1)
+	for i := first; i < last; i++ {
+		utterance := utterances[i]
+		line := fmt.Sprintf("[%s] Speaker %s: %s", transcribe.FormatTimestamp(float64(utterance.Start)/1000.0), utterance.Speaker, strings.TrimSpace(utterance.Text))
+		if i == current {
+			output.WriteString("\033[7m" + line + "\033[0m\n")
+		} else {
+			output.WriteString(line + "\n")
+		}
+	}
+
+	return output.String()
+}
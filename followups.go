@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"transcribe/pkg/transcribe"
+)
+
+// followUp is a question from the meeting that was left unanswered
+type followUp struct {
+	Question string `json:"question"`
+	Speaker  string `json:"-"`
+	Start    int    `json:"-"`
+}
+
+const followUpsPrompt = `List the questions that were asked in this meeting but not answered, or that
+someone promised to follow up on later. Copy each question verbatim from the transcript
+without changing any words. Respond with only a JSON array of objects with the field
+"question".`
+
+// extractFollowUps asks LeMUR for unresolved questions and locates each of them in
+// the utterances to attach the speaker and timestamp
+func extractFollowUps(transcription *TranscriptionResponse, model, apiKey string) ([]followUp, error) {
+	answer, err := runLemurTask(transcription.ID, followUpsPrompt, model, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []followUp
+	if err := parseLemurJSON(answer, &candidates); err != nil {
+		return nil, err
+	}
+
+	var followUps []followUp
+	for _, candidate := range candidates {
+		utterance, ok := locateText(transcription.Utterances, candidate.Question)
+		if !ok {
+			warnf("dropping follow-up not found verbatim in the transcript: %q", candidate.Question)
+			continue
+		}
+		followUps = append(followUps, followUp{Question: strings.TrimSpace(candidate.Question), Speaker: utterance.Speaker, Start: utterance.Start})
+	}
+	return followUps, nil
+}
+
+// renderFollowUps formats the follow-ups as an agenda-ready list
+func renderFollowUps(followUps []followUp) string {
+	var output strings.Builder
+	output.WriteString("Open questions and follow-ups:\n")
+	if len(followUps) == 0 {
+		output.WriteString("  (none)\n")
+	}
+	for _, f := range followUps {
+		output.WriteString(fmt.Sprintf("  [%s] Speaker %s: %s\n", transcribe.FormatTimestamp(float64(f.Start)/1000.0), f.Speaker, f.Question))
+	}
+	return output.String()
+}
+
+// saveFollowUps writes the follow-ups to filename
+func saveFollowUps(filename string, followUps []followUp) error {
+	return os.WriteFile(filename, []byte(renderFollowUps(followUps)), 0644)
+}
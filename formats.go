@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"transcribe/pkg/transcribe"
+)
+
+// parseFormats parses a comma-separated --format value into the format names,
+// dropping repeats
+func parseFormats(value string) ([]string, error) {
+	var formats []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := transcribe.Formats[name]; !ok {
+			return nil, fmt.Errorf("unknown output format: %s", name)
+		}
+		if !slices.Contains(formats, name) {
+			formats = append(formats, name)
+		}
+	}
+	return formats, nil
+}
+
+// registerFormatFlags adds a --<format>.<name> flag for every format option and
+// returns the values by "<format>.<name>"
+func registerFormatFlags(fs *flag.FlagSet) map[string]*string {
+	values := make(map[string]*string)
+	for _, name := range transcribe.FormatNames() {
+		for _, option := range transcribe.Formats[name].Options {
+			key := name + "." + option.Name
+			values[key] = fs.String(key, option.Default, fmt.Sprintf("%s output: %s", name, option.Usage))
+		}
+	}
+	return values
+}
+
+// parseFormatOptions validates the format option flags and returns their values
+func parseFormatOptions(values map[string]*string) (map[string]string, error) {
+	options := make(map[string]string)
+	for _, name := range transcribe.FormatNames() {
+		for _, option := range transcribe.Formats[name].Options {
+			key := name + "." + option.Name
+			value := *values[key]
+			if option.Int {
+				if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+					return nil, fmt.Errorf("invalid --%s: %s", key, value)
+				}
+			}
+			options[key] = value
+		}
+	}
+	return options, nil
+}
+
+// subtitleFormats are the output formats that --subtitle-rates applies to
+var subtitleFormats = map[string]bool{"srt": true, "vtt": true, "ttml": true, "ass": true}
+
+// parsePlaybackRates parses a comma-separated list of playback rates such as
+// "1.25,1.5"
+func parsePlaybackRates(value string) ([]float64, error) {
+	var rates []float64
+	for _, part := range strings.Split(value, ",") {
+		rate, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid playback rate: %s", part)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
@@ -2,4 +2,7 @@ module transcribe
 
 go 1.25.3
 
-require github.com/joho/godotenv v1.5.1
+require (
+	github.com/joho/godotenv v1.5.1
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// copyRetries is how many times a failed read from a network share is retried
+	copyRetries = 5
+	// copyProgressInterval is how often progress is printed while copying
+	copyProgressInterval = 5 * time.Second
+)
+
+// resolveInput turns a file:// URL into a local or UNC path; other arguments are
+// returned unchanged
+func resolveInput(arg string) (string, error) {
+	if !strings.HasPrefix(arg, "file://") {
+		return arg, nil
+	}
+
+	u, err := url.Parse(arg)
+	if err != nil {
+		return "", fmt.Errorf("invalid file URL: %w", err)
+	}
+	path := u.Path
+	if u.Host != "" && u.Host != "localhost" {
+		// file://server/share/file.mp4 names a file on a network share
+		path = "//" + u.Host + path
+	} else if runtime.GOOS == "windows" && len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		// file:///C:/recordings/file.mp4
+		path = path[1:]
+	}
+	return filepath.FromSlash(path), nil
+}
+
+// isNetworkPath reports whether path is a UNC path on a network share
+func isNetworkPath(path string) bool {
+	return strings.HasPrefix(path, `\\`) || strings.HasPrefix(path, "//")
+}
+
+// copyToLocal copies a file, typically from a network share, into a local temp file
+// so that ffmpeg and ffprobe read it from fast, reliable storage. Reads that fail
+// are retried from the same offset, and progress is printed while copying.
+func copyToLocal(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	tmpFile, err := os.CreateTemp("", "transcribe-input-*"+filepath.Ext(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	var copied int64
+	lastProgress := time.Now()
+	buf := make([]byte, 1<<20)
+	for attempt := 0; copied < info.Size(); {
+		n, err := copyFrom(path, copied, tmpFile, buf, func(total int64) {
+			if time.Since(lastProgress) >= copyProgressInterval {
+				fmt.Fprintf(status, "Copying %s: %.0f%%\n", filepath.Base(path), 100*float64(total)/float64(info.Size()))
+				lastProgress = time.Now()
+			}
+		})
+		copied += n
+		if err == nil {
+			break
+		}
+		if n == 0 {
+			attempt++
+		} else {
+			attempt = 0
+		}
+		if attempt >= copyRetries {
+			tmpFile.Close()
+			os.Remove(tmpFile.Name())
+			return "", fmt.Errorf("failed to copy %s: %w", path, err)
+		}
+		warnf("read from %s failed at %s, retrying: %v", path, formatBytes(copied), err)
+		time.Sleep(time.Duration(attempt+1) * time.Second)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	// Keep the modification time, which is used to date the recording
+	os.Chtimes(tmpFile.Name(), info.ModTime(), info.ModTime())
+	return tmpFile.Name(), nil
+}
+
+// copyFrom copies path from offset into dst until EOF, returning the number of
+// bytes written. progress is called with the offset reached.
+func copyFrom(path string, offset int64, dst io.Writer, buf []byte, progress func(int64)) (int64, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	var written int64
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return written, werr
+			}
+			written += int64(n)
+			progress(offset + written)
+		}
+		if errors.Is(err, io.EOF) {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
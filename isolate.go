@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// isolateVoice runs the demucs source-separation model on audioFile to strip
+// background music and noise, returning a new temporary MP3 with only the vocals.
+// With strict privacy the separated stems are shredded rather than just removed.
+func isolateVoice(audioFile string, strict bool) (string, error) {
+	outDir, err := os.MkdirTemp("", "transcribe-demucs-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() {
+		remove := os.RemoveAll
+		if strict {
+			remove = shredDir
+		}
+		if err := remove(outDir); err != nil {
+			warnf("failed to remove the demucs output: %v", err)
+		}
+	}()
+
+	cmd := exec.Command("demucs", "--two-stems=vocals", "-o", outDir, audioFile)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("demucs failed: %w\nOutput: %s", err, stderr.String())
+	}
+
+	// demucs writes <out>/<model>/<track>/vocals.wav
+	matches, err := filepath.Glob(filepath.Join(outDir, "*", "*", "vocals.*"))
+	if err != nil || len(matches) == 0 {
+		return "", fmt.Errorf("demucs produced no vocals track")
+	}
+
+	tmpFile, err := os.CreateTemp("", "transcribe-*.mp3")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpFile.Close()
+
+	if err := runFFmpeg("-i", matches[0], "-acodec", "libmp3lame", "-q:a", "2", tmpFile.Name(), "-y"); err != nil {
+		if strict {
+			shredFile(tmpFile.Name())
+		} else {
+			os.Remove(tmpFile.Name())
+		}
+		return "", err
+	}
+
+	return tmpFile.Name(), nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LemurTaskRequest represents a request to run a custom prompt over transcripts,
+// or over InputText instead
+type LemurTaskRequest struct {
+	TranscriptIDs []string `json:"transcript_ids,omitempty"`
+	InputText     string   `json:"input_text,omitempty"`
+	Prompt        string   `json:"prompt"`
+	FinalModel    string   `json:"final_model,omitempty"`
+}
+
+// LemurResponse represents the LeMUR task response
+type LemurResponse struct {
+	RequestID string `json:"request_id"`
+	Response  string `json:"response"`
+}
+
+// runLemurTask runs prompt over the transcript with LeMUR and returns the model's answer
+func runLemurTask(transcriptID, prompt, model, apiKey string) (string, error) {
+	return sendLemurTask(LemurTaskRequest{TranscriptIDs: []string{transcriptID}, Prompt: prompt, FinalModel: model}, apiKey)
+}
+
+// runLemurText runs prompt over text instead of a stored transcript, for text that
+// was changed after transcription
+func runLemurText(text, prompt, model, apiKey string) (string, error) {
+	return sendLemurTask(LemurTaskRequest{InputText: text, Prompt: prompt, FinalModel: model}, apiKey)
+}
+
+// sendLemurTask sends a task request and returns the model's answer
+func sendLemurTask(task LemurTaskRequest, apiKey string) (string, error) {
+	jsonData, err := json.Marshal(task)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", lemurBaseURL()+"/generate/task", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("LeMUR request failed with %w", &APIError{StatusCode: resp.StatusCode, Body: string(body)})
+	}
+
+	var lemurResp LemurResponse
+	if err := json.Unmarshal(body, &lemurResp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return strings.TrimSpace(lemurResp.Response), nil
+}
+
+// parseLemurJSON decodes a JSON answer from the model, tolerating a surrounding
+// Markdown code fence
+func parseLemurJSON(answer string, v any) error {
+	answer = strings.TrimSpace(answer)
+	if strings.HasPrefix(answer, "```") {
+		answer = strings.TrimPrefix(answer, "```json")
+		answer = strings.TrimPrefix(answer, "```")
+		answer = strings.TrimSuffix(strings.TrimSpace(answer), "```")
+	}
+	if err := json.Unmarshal([]byte(answer), v); err != nil {
+		return fmt.Errorf("failed to parse model answer: %w", err)
+	}
+	return nil
+}
+
+// normalizeForMatch lowercases text and reduces it to its words so that text taken
+// from a model answer can be found regardless of punctuation and spacing
+func normalizeForMatch(text string) string {
+	return strings.Join(wordPattern.FindAllString(strings.ToLower(text), -1), " ")
+}
+
+// locateText finds the utterance that contains text verbatim. Models are asked to
+// copy from the transcript; the utterance gives the speaker and timestamps.
+func locateText(utterances []Utterance, text string) (Utterance, bool) {
+	needle := normalizeForMatch(text)
+	if needle == "" {
+		return Utterance{}, false
+	}
+	for _, utterance := range utterances {
+		if strings.Contains(normalizeForMatch(utterance.Text), needle) {
+			return utterance, true
+		}
+	}
+	return Utterance{}, false
+}
@@ -2,307 +2,1398 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"text/template"
 	"time"
 
-	"github.com/joho/godotenv"
+	"transcribe/pkg/transcribe"
 )
 
-const (
-	assemblyAIBaseURL = "https://api.assemblyai.com/v2"
+// The API types live in the library package; these names are kept for the CLI
+type (
+	// Utterance represents a single transcribed utterance with speaker info
+	Utterance = transcribe.Segment
+	// Chapter represents an automatically detected chapter
+	Chapter = transcribe.Chapter
+	// TranscriptionResponse represents the API response
+	TranscriptionResponse = transcribe.Result
+	// APIError is returned when the API responds with a non-OK status
+	APIError = transcribe.APIError
 )
 
-// Utterance represents a single transcribed utterance with speaker info
-type Utterance struct {
-	Speaker    string  `json:"speaker"`
-	Start      int     `json:"start"`
-	End        int     `json:"end"`
-	Text       string  `json:"text"`
-	Confidence float64 `json:"confidence"`
-}
+// status receives progress messages; it is switched to stderr when stdout carries
+// machine-readable output
+var status io.Writer = os.Stdout
 
-// TranscriptRequest represents the request to create a transcript
-type TranscriptRequest struct {
-	AudioURL      string `json:"audio_url"`
-	SpeakerLabels bool   `json:"speaker_labels"`
-}
+// warnings collects the warnings printed during the run
+var warnings []string
 
-// TranscriptionResponse represents the API response
-type TranscriptionResponse struct {
-	ID         string      `json:"id"`
-	Status     string      `json:"status"`
-	Text       string      `json:"text"`
-	Utterances []Utterance `json:"utterances"`
-	Error      string      `json:"error"`
+// warnf prints a warning and records it for the run result
+func warnf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	warnings = append(warnings, msg)
+	fmt.Fprintf(status, "Warning: %s\n", msg)
 }
 
-// UploadResponse represents the upload endpoint response
-type UploadResponse struct {
-	UploadURL string `json:"upload_url"`
+func main() {
+	if len(os.Args) > 1 {
+		if cmd := findCommand(os.Args[1]); cmd != nil {
+			if err := cmd.Run(os.Args[2:]); err != nil {
+				fmt.Printf("%s: %v\n", cmd.Failure, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	// Without a subcommand the arguments are those of run
+	runTranscription(os.Args[1:])
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: transcribe <video-file>")
+// runTranscription implements the run subcommand: it transcribes the input and
+// writes the outputs, exiting the process on failure
+func runTranscription(arguments []string) {
+	format := flag.String("format", "txt", "comma-separated output formats: "+strings.Join(transcribe.FormatNames(), ", "))
+	plain := flag.Bool("plain", false, "write the txt output as plain prose, one paragraph per speaker turn without timestamps or speakers (--format plain)")
+	formatFlags := registerFormatFlags(flag.CommandLine)
+	subtitleSpeakers := flag.Bool("subtitle-speakers", true, "mark subtitles with the speaker label (a voice span in vtt)")
+	subtitleRates := flag.String("subtitle-rates", "", "also write subtitles retimed for these comma-separated playback rates, e.g. 1.25,1.5")
+	stylesFile := flag.String("styles", "", "styles `file` with the caption color, font and position of each speaker, applied to vtt, ttml, ass, --player and --burn-subtitles")
+	burnSubtitlesFlag := flag.Bool("burn-subtitles", false, "also write a copy of the video with the subtitles burned into the picture")
+	frameRate := flag.Float64("frame-rate", 0, "frame rate of TTML, FCPXML and Premiere marker times, e.g. 25 or 29.97 (default: TTML uses clock time)")
+	citationStyle := flag.String("citation-style", "apa", "citation style for --format citations: apa or bibtex")
+	sourceTitle := flag.String("source-title", "", "source title used in citations (default: the media file name)")
+	modelFallback := flag.String("model-fallback", "", "comma-separated speech models to try in order when a model is unavailable")
+	agendaFile := flag.String("agenda", "", "agenda file with \"<timestamp> <heading>\" lines to inject as section headings")
+	chapters := flag.Bool("chapters", false, "detect chapters in the audio")
+	embedChapters := flag.String("embed-chapters", "", "also export the audio as `m4b|mp3` with the detected chapters embedded (implies --chapters)")
+	tagMediaFlag := flag.Bool("tag-media", false, "write a copy of the input tagged with the title, language, summary and transcript ID")
+	preflight := flag.Bool("preflight", false, "analyze audio quality and print a report before transcribing")
+	preflightOnly := flag.Bool("preflight-only", false, "only run the audio quality check, without transcribing")
+	dedupeEcho := flag.Bool("dedupe-echo", false, "detect channels that carry the same audio with a delay and transcribe only the leading one")
+	var mirrors stringList
+	flag.Var(&mirrors, "mirror", "alternative `URL` of a URL input, tried in order when the download fails (repeatable)")
+	localCopy := flag.Bool("local-copy", false, "copy the input to local temp storage first (always done for UNC paths)")
+	fromResponse := flag.String("from-response", "", "reprocess a stored API response `file` instead of transcribing (see transcribe reprocess)")
+	keepRawResponses := flag.String("keep-raw-responses", "", "store the exact API response, gzipped, in `dir` for reprocessing later")
+	sidecarFlag := flag.Bool("sidecar", true, "also write the transcript as returned by the API, with repaired times, to <output>.json")
+	lowPower := flag.Bool("low-power", false, "tune for small devices such as a Raspberry Pi: single-threaded 16 kHz mono conversion, no local models")
+	listTitles := flag.Bool("list-titles", false, "list the titles and audio tracks of the input and exit")
+	titleFlag := flag.Int("title", -1, "transcribe this title (program) of a multi-title container such as a Blu-ray M2TS")
+	audioTrack := flag.Int("audio-track", -1, "transcribe this audio track (0-based, see --list-titles)")
+	channelsFlag := flag.String("channels", "", "mix only these comma-separated channels (1-based) to mono before transcribing")
+	profileFlag := flag.String("profile", "", "apply a saved preprocessing profile (default: the profile matching the file name; \"none\" to disable)")
+	maskDTMFFlag := flag.Bool("mask-dtmf", false, "silence keypad (DTMF) tones such as keyed card numbers before upload and redact the transcript over them")
+	isolateVoiceFlag := flag.Bool("isolate-voice", false, "strip background music with the demucs source-separation model before transcribing")
+	languageFlag := flag.String("language", "", languageUsage)
+	dialectFlag := flag.String("dialect", "", dialectUsage())
+	promptFlag := flag.String("prompt", "", "domain vocabulary to favor in recognition, as comma-separated names and phrases")
+	promptFile := flag.String("prompt-file", "", "read vocabulary for --prompt from `file`, one term per line")
+	speakers := flag.Int("speakers", 0, "expected number of speakers, used as a diarization hint")
+	splitOutput := flag.String("split-output", "", "write numbered output files split `by=chapter|hour|speaker-turns=N` plus an index file")
+	terminologyFile := flag.String("terminology", "", "terminology memory file used to keep name and term spellings consistent across runs")
+	var ignoreRanges, ignoreSpeakers stringList
+	flag.Var(&ignoreRanges, "ignore-range", "exclude segments starting in `HH:MM-HH:MM` from the outputs (repeatable)")
+	flag.Var(&ignoreSpeakers, "ignore-speaker", "exclude a speaker such as \"Speaker C\" from the outputs (repeatable)")
+	rolesFlag := flag.String("roles", "", "label speakers with their role: interview (host/guest), support (agent/customer) or auto")
+	pipelineFlag := flag.String("pipeline", "", pipelineUsage())
+	notesPath := flag.String("notes", "", "reviewer annotations file (default: <video-file>.notes.yaml if it exists)")
+	detectDate := flag.Bool("detect-date", false, "detect the recording date from metadata and the spoken content and stamp it on the transcript")
+	autoNameFlag := flag.Bool("auto-name", false, "name output files after the recording date and transcript content")
+	outputPath := flag.String("output", "", "write outputs to this path, or into this directory; - prints the transcript to stdout (default: next to the input)")
+	flag.StringVar(outputPath, "o", "", "shorthand for --output")
+	outputTemplateFlag := flag.String("output-template", "", "build the output path from a `template` such as {{.Dir}}/transcripts/{{.Name}}-{{.Date}}.{{.Ext}}")
+	exportRegions := flag.String("export-regions", "", "also export segment boundaries for editors: reaper, protools or premiere")
+	sampleRate := flag.Int("sample-rate", 48000, "sample rate used by --export-regions")
+	autoSplitSessions := flag.Bool("auto-split-sessions", false, "write separate outputs for sessions separated by long silences")
+	sessionGap := flag.Duration("session-gap", 10*time.Minute, "silence that separates sessions for --auto-split-sessions")
+	meetingReportFlag := flag.Bool("meeting-report", false, "also write an HTML report of talk time, questions, interruptions and monologues")
+	coachFlag := flag.Bool("coach", false, "also write an HTML coaching report of speaking pace, pauses, filler density and pitch range")
+	quotesFlag := flag.Bool("quotes", false, "also write the most quotable statements per speaker, selected with LeMUR")
+	quotesPerSpeaker := flag.Int("quotes-per-speaker", 3, "maximum number of quotes per speaker for --quotes")
+	followUpsFlag := flag.Bool("follow-ups", false, "also write the questions left unanswered in the meeting, selected with LeMUR")
+	playerHTML := flag.Bool("player", false, "also write an HTML transcript that follows playback and seeks on click")
+	embedAudio := flag.Bool("embed-audio", false, "embed the audio in the --player page instead of linking the source media, as is always done for URL inputs")
+	exportSnippetsFlag := flag.String("export-snippets", "", "sample `n=N` segments into an HTML page with their audio for spot checks")
+	maxCost := flag.Float64("max-cost", 0, "refuse to transcribe when the estimated cost in USD exceeds this amount")
+	monthlyBudget := flag.Float64("monthly-budget", 0, "monthly budget in USD, checked against the spend recorded in the usage ledger")
+	pricePerHour := flag.Float64("price-per-hour", 0, "price in USD per audio hour used for cost estimates (default: list price of the model)")
+	apiKeyFlag := flag.String("api-key", "", apiKeyUsage)
+	flag.StringVar(&apiURL, "api-url", "", apiURLUsage)
+	privacy := flag.String("privacy", "", "privacy mode; \"strict\" shreds temp files, deletes the transcript from the API and learns no terms")
+	signKey := flag.String("sign", "", "embed the source media checksum in the transcript and sign it with the minisign secret key `file`")
+	flag.DurationVar(&stallTimeout, "stall-timeout", stallTimeout, "kill and retry an FFmpeg process, download or API request that makes no progress for this long (0 disables)")
+	maxMemory := flag.String("max-memory", "", "keep the heap below this `size` (e.g. 512M, 2GiB) by collecting garbage more aggressively")
+	toStdout := flag.Bool("stdout", false, "print the transcript to stdout instead of a file, moving status messages to stderr (same as --output -)")
+	resultJSON := flag.String("result-json", "", "write a JSON summary of the run to `file` (- for stdout, moving status messages to stderr)")
+	flag.Usage = func() {
+		printUsage()
+		fmt.Fprintln(os.Stderr, "\nFlags of run:")
+		flag.PrintDefaults()
+	}
+	args := parseArgs(flag.CommandLine, arguments)
+	if err := applyConfig(flag.CommandLine); err != nil {
+		fmt.Fprintf(status, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 
-	videoFile := os.Args[1]
+	if len(args) < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
 
-	// Load API key from .env
-	err := godotenv.Load()
+	videoFile, err := resolveInput(args[0])
 	if err != nil {
-		fmt.Printf("Error loading .env file: %v\n", err)
+		fmt.Fprintf(status, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	apiKey := os.Getenv("ASSEMBLYAI_API_KEY")
-	if apiKey == "" {
-		fmt.Println("Error: ASSEMBLYAI_API_KEY not found in .env")
+	if *outputPath == "-" {
+		*toStdout = true
+		*outputPath = ""
+	}
+	if *resultJSON == "-" || *toStdout {
+		status = os.Stderr
+	}
+	result := runResult{Input: videoFile, Outputs: []string{}}
+	strict := false
+	mp3File := ""
+	removeTemp := func() error {
+		if mp3File == "" {
+			return nil
+		}
+		path := mp3File
+		mp3File = ""
+		if strict {
+			return shredFile(path)
+		}
+		return os.Remove(path)
+	}
+	// mediaFile is read for audio and metadata; it is a local copy of videoFile when
+	// the input lives on a network share
+	mediaFile := videoFile
+	removeCopy := func() {
+		if mediaFile == videoFile {
+			return
+		}
+		if strict {
+			shredFile(mediaFile)
+		} else {
+			os.Remove(mediaFile)
+		}
+		mediaFile = videoFile
+	}
+	fail := func(format string, args ...any) {
+		removeTemp()
+		removeCopy()
+		fmt.Fprintf(status, format+"\n", args...)
+		if *resultJSON != "" {
+			result.Error = fmt.Sprintf(format, args...)
+			writeResult(*resultJSON, &result)
+		}
 		os.Exit(1)
 	}
 
-	// Convert video to MP3
-	fmt.Println("Converting video to MP3...")
-	mp3File, err := convertToMP3(videoFile)
-	if err != nil {
-		fmt.Printf("Error converting video: %v\n", err)
-		os.Exit(1)
+	var models []string
+	if *modelFallback != "" {
+		for _, model := range strings.Split(*modelFallback, ",") {
+			models = append(models, strings.TrimSpace(model))
+		}
+	}
+	spoken, ok := dialects[*dialectFlag]
+	if *dialectFlag != "" && !ok {
+		fail("Error: unknown dialect: %s", *dialectFlag)
+	}
+	if len(models) == 0 && spoken.Model != "" {
+		models = []string{spoken.Model}
 	}
-	defer os.Remove(mp3File)
 
-	// Upload audio file
-	fmt.Println("Uploading audio file...")
-	uploadURL, err := uploadAudio(mp3File, apiKey)
+	policy, err := enforcePolicy(flag.CommandLine, models...)
 	if err != nil {
-		fmt.Printf("Error uploading audio: %v\n", err)
-		os.Exit(1)
+		fail("Error: %v", err)
+	}
+	if policy != nil && policy.Privacy != "" {
+		*privacy = policy.Privacy
+	}
+
+	strict = *privacy == "strict"
+	if *privacy != "" && !strict {
+		fail("Error: unknown privacy mode: %s", *privacy)
 	}
 
-	// Transcribe with diarization
-	fmt.Println("Transcribing audio with speaker diarization...")
-	transcription, err := transcribeAudio(uploadURL, apiKey)
+	if *quotesFlag && strict {
+		fail("Error: --quotes sends the transcript to LeMUR and is not available with --privacy strict")
+	}
+	if *keepRawResponses != "" && strict {
+		fail("Error: --keep-raw-responses keeps an archive and is not available with --privacy strict")
+	}
+	if *followUpsFlag && strict {
+		fail("Error: --follow-ups sends the transcript to LeMUR and is not available with --privacy strict")
+	}
+
+	formats, err := parseFormats(*format)
 	if err != nil {
-		fmt.Printf("Error transcribing audio: %v\n", err)
-		os.Exit(1)
+		fail("Error: %v", err)
+	}
+	if *plain {
+		if i := slices.Index(formats, "txt"); i >= 0 {
+			formats[i] = "plain"
+		} else if !slices.Contains(formats, "plain") {
+			formats = append(formats, "plain")
+		}
+	}
+
+	var memoryLimit int64
+	if *maxMemory != "" {
+		memoryLimit, err = parseMemorySize(*maxMemory)
+		if err != nil {
+			fail("Error: %v", err)
+		}
+		setMemoryLimit(memoryLimit)
 	}
 
-	// Save to output file
-	outputFile := strings.TrimSuffix(videoFile, filepath.Ext(videoFile)) + ".txt"
-	err = saveTranscription(outputFile, transcription)
+	if *citationStyle != "apa" && *citationStyle != "bibtex" {
+		fail("Error: unknown citation style: %s", *citationStyle)
+	}
+	formatOptions, err := parseFormatOptions(formatFlags)
 	if err != nil {
-		fmt.Printf("Error saving transcription: %v\n", err)
-		os.Exit(1)
+		fail("Error: %v", err)
 	}
 
-	fmt.Printf("Transcription saved to: %s\n", outputFile)
-}
+	// Formats writing the same extension would overwrite each other
+	extensions := make(map[string]string)
+	writtenBy := make(map[string]string)
+	for _, name := range formats {
+		extension := transcribe.Formats[name].Extension
+		if name == "citations" && *citationStyle == "bibtex" {
+			extension = ".bib"
+		}
+		if other, ok := writtenBy[extension]; ok {
+			fail("Error: formats %s and %s both write %s files; request them in separate runs", other, name, extension)
+		}
+		writtenBy[extension] = name
+		extensions[name] = extension
+	}
 
-// convertToMP3 converts a video file to MP3 format using FFmpeg
-func convertToMP3(videoFile string) (string, error) {
-	// Check if input file exists
-	if _, err := os.Stat(videoFile); os.IsNotExist(err) {
-		return "", fmt.Errorf("video file does not exist: %s", videoFile)
+	if _, ok := roleSets[*rolesFlag]; *rolesFlag != "" && *rolesFlag != "auto" && !ok {
+		fail("Error: unknown role set: %s", *rolesFlag)
 	}
 
-	// Create temporary MP3 file
-	tmpFile, err := os.CreateTemp("", "transcribe-*.mp3")
+	pipelineValue := *pipelineFlag
+	if pipelineValue == "" {
+		pipelineValue = defaultPipeline
+	}
+	pipeline, err := parsePipeline(pipelineValue)
 	if err != nil {
-		return "", fmt.Errorf("failed to create temp file: %w", err)
+		fail("Error: %v", err)
+	}
+	if *pipelineFlag != "" {
+		// The flags that configure a stage do nothing unless the stage runs
+		for _, configured := range []struct {
+			stage, flags string
+			set          bool
+		}{
+			{"ignore", "--ignore-range and --ignore-speaker", len(ignoreRanges) > 0 || len(ignoreSpeakers) > 0},
+			{"terminology", "--terminology", *terminologyFile != ""},
+			{"roles", "--roles", *rolesFlag != ""},
+		} {
+			if configured.set && !hasStep(pipeline, configured.stage) {
+				fail("Error: --pipeline has no %s stage for %s", configured.stage, configured.flags)
+			}
+		}
+		for _, step := range pipeline {
+			if step.Arg == "" && (step.Name == "terminology" && *terminologyFile == "" || step.Name == "roles" && *rolesFlag == "") {
+				fail("Error: pipeline stage %s needs a value: %s:%s or --%s", step.Name, step.Name, pipelineStages[step.Name].Arg, step.Name)
+			}
+		}
+		if policy != nil {
+			if err := policy.checkStages(pipeline); err != nil {
+				fail("Error: %v", err)
+			}
+		}
+	}
+	if usesLeMUR(pipeline) && strict {
+		fail("Error: --pipeline sends the transcript to LeMUR and is not available with --privacy strict")
 	}
-	tmpFile.Close()
 
-	mp3Path := tmpFile.Name()
+	if slices.Contains(formats, "dataset") && *splitOutput != "" {
+		fail("Error: --split-output does not apply to --format dataset")
+	}
 
-	// Run FFmpeg to convert video to MP3
-	cmd := exec.Command("ffmpeg", "-i", videoFile, "-vn", "-acodec", "libmp3lame", "-q:a", "2", mp3Path, "-y")
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+	if *lowPower && *isolateVoiceFlag {
+		fail("Error: --isolate-voice runs the demucs model, which is too heavy for --low-power")
+	}
+	if *maskDTMFFlag && *keepRawResponses != "" {
+		// The archived response is untouched, so it would keep the digits read out
+		// over the keypad tones that --mask-dtmf redacts
+		fail("Error: --keep-raw-responses archives the unredacted response and is not available with --mask-dtmf")
+	}
 
-	if err := cmd.Run(); err != nil {
-		os.Remove(mp3Path)
-		return "", fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, stderr.String())
+	var selectedChannels []int
+	if *channelsFlag != "" {
+		selectedChannels, err = parseChannels(*channelsFlag)
+		if err != nil {
+			fail("Error: %v", err)
+		}
+		if *dedupeEcho {
+			fail("Error: --channels and --dedupe-echo both choose channels; use one of them")
+		}
 	}
 
-	return mp3Path, nil
-}
+	var playbackRates []float64
+	if *subtitleRates != "" {
+		if !slices.ContainsFunc(formats, func(name string) bool { return subtitleFormats[name] }) {
+			fail("Error: --subtitle-rates needs a subtitle format: srt, vtt or ttml")
+		}
+		playbackRates, err = parsePlaybackRates(*subtitleRates)
+		if err != nil {
+			fail("Error: %v", err)
+		}
+	}
 
-// uploadAudio uploads an audio file to AssemblyAI and returns the upload URL
-func uploadAudio(audioFile, apiKey string) (string, error) {
-	file, err := os.Open(audioFile)
-	if err != nil {
-		return "", fmt.Errorf("failed to open audio file: %w", err)
+	var split splitMode
+	if *splitOutput != "" {
+		var err error
+		split, err = parseSplitMode(*splitOutput)
+		if err != nil {
+			fail("Error: %v", err)
+		}
 	}
-	defer file.Close()
 
-	req, err := http.NewRequest("POST", assemblyAIBaseURL+"/upload", file)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+	if *toStdout {
+		switch {
+		case len(formats) > 1:
+			fail("Error: --stdout prints a single format; choose one with --format")
+		case formats[0] == "dataset":
+			fail("Error: --format dataset writes a directory and cannot go to stdout")
+		case split.By != "":
+			fail("Error: --split-output writes several files and cannot go to stdout")
+		case *resultJSON == "-":
+			fail("Error: --stdout and --result-json - both write to stdout; use one of them")
+		case *signKey != "":
+			fail("Error: --sign needs a transcript file to sign and is not available with --stdout")
+		}
 	}
 
-	req.Header.Set("Authorization", apiKey)
-	req.Header.Set("Content-Type", "application/octet-stream")
+	if *embedChapters != "" {
+		if _, ok := chapterAudioCodecs[*embedChapters]; !ok {
+			fail("Error: unsupported chapter audio format: %s", *embedChapters)
+		}
+		*chapters = true
+	}
 
-	client := &http.Client{Timeout: 10 * time.Minute}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+	var ranges []timeRange
+	for _, value := range ignoreRanges {
+		r, err := parseTimeRange(value)
+		if err != nil {
+			fail("Error: %v", err)
+		}
+		ranges = append(ranges, r)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	if *exportRegions != "" && *exportRegions != "reaper" && *exportRegions != "protools" && *exportRegions != "premiere" {
+		fail("Error: unknown region format: %s", *exportRegions)
+	}
+	if *exportRegions == "premiere" && *frameRate <= 0 {
+		fail("Error: --export-regions premiere needs the --frame-rate of the sequence")
+	}
+	if slices.Contains(formats, "fcpxml") && *frameRate <= 0 {
+		fail("Error: --format fcpxml needs the --frame-rate of the project")
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, string(body))
+	var outputTemplate *template.Template
+	if *outputTemplateFlag != "" {
+		if *outputPath != "" {
+			fail("Error: --output and --output-template both set the output path; use one of them")
+		}
+		var err error
+		outputTemplate, err = parseOutputTemplate(*outputTemplateFlag)
+		if err != nil {
+			fail("Error: %v", err)
+		}
 	}
 
-	var uploadResp UploadResponse
-	if err := json.Unmarshal(body, &uploadResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+	snippetCount := 0
+	if *exportSnippetsFlag != "" {
+		var err error
+		snippetCount, err = parseSnippetCount(*exportSnippetsFlag)
+		if err != nil {
+			fail("Error: %v", err)
+		}
 	}
 
-	return uploadResp.UploadURL, nil
-}
+	// downloaded is set for a URL input, whose download is deleted after the run
+	downloaded := false
+	if isURL(videoFile) {
+		downloaded = true
+		fmt.Fprintf(status, "Downloading %s...\n", videoFile)
+		var name string
+		mediaFile, name, err = downloadInput(append([]string{videoFile}, mirrors...))
+		if err != nil {
+			fail("Error downloading input: %v", err)
+		}
+		// Outputs go to the current directory, named after the download
+		videoFile = name
+		fmt.Fprintf(status, "Download saved to: %s\n", mediaFile)
+		defer removeCopy()
+	} else if len(mirrors) > 0 {
+		fail("Error: --mirror applies to URL inputs only")
+	} else if *localCopy || isNetworkPath(videoFile) {
+		fmt.Fprintf(status, "Copying %s to local storage...\n", videoFile)
+		mediaFile, err = copyToLocal(videoFile)
+		if err != nil {
+			fail("Error copying input: %v", err)
+		}
+		defer removeCopy()
+	}
 
-// transcribeAudio submits audio for transcription and polls until complete
-func transcribeAudio(audioURL, apiKey string) (*TranscriptionResponse, error) {
-	// Submit transcription request
-	requestData := TranscriptRequest{
-		AudioURL:      audioURL,
-		SpeakerLabels: true,
+	if *burnSubtitlesFlag && !videoExtensions[strings.ToLower(filepath.Ext(videoFile))] {
+		fail("Error: --burn-subtitles needs a video input")
 	}
 
-	jsonData, err := json.Marshal(requestData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	if *listTitles {
+		programs, streams, err := probeTitles(mediaFile)
+		if err != nil {
+			fail("Error listing titles: %v", err)
+		}
+		fmt.Fprint(status, titlesReport(programs, streams))
+		removeCopy()
+		return
 	}
 
-	req, err := http.NewRequest("POST", assemblyAIBaseURL+"/transcript", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	if *preflight || *preflightOnly {
+		quality, err := analyzeAudio(mediaFile)
+		if err != nil {
+			fail("Error analyzing audio: %v", err)
+		}
+		fmt.Fprint(status, quality.report())
+		if *preflightOnly {
+			return
+		}
 	}
 
-	req.Header.Set("Authorization", apiKey)
-	req.Header.Set("Content-Type", "application/json")
+	if err := checkAPIURL(); err != nil {
+		fail("Error: %v", err)
+	}
+	apiKey, err := loadAPIKey(*apiKeyFlag)
+	if err != nil && *fromResponse == "" {
+		fail("Error: %v", err)
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	language, err := parseLanguage(*languageFlag)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		fail("Error: %v", err)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	if language == "" {
+		language = spoken.Language
+	}
+	prompt, err := loadPrompt(*promptFlag, *promptFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		fail("Error: %v", err)
 	}
+	boostTerms := promptTerms(strings.Join(append([]string{prompt}, spoken.terms()...), "\n"))
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("transcription request failed with status %d: %s", resp.StatusCode, string(body))
+	var render transcribe.RenderOptions
+	if *agendaFile != "" {
+		render.Agenda, err = loadAgenda(*agendaFile)
+		if err != nil {
+			fail("Error loading agenda: %v", err)
+		}
+	}
+	if *stylesFile != "" {
+		render.Styles, err = loadStyles(*stylesFile)
+		if err != nil {
+			fail("Error loading styles: %v", err)
+		}
 	}
 
-	var transcription TranscriptionResponse
-	if err := json.Unmarshal(body, &transcription); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	convert := convertOptions{LowPower: *lowPower, Map: trackMap(*titleFlag, *audioTrack)}
+	if *dedupeEcho {
+		fmt.Fprintln(status, "Checking for duplicated audio...")
+		channel, err := detectDoubleCapture(mediaFile)
+		if err != nil {
+			fail("Error checking for duplicated audio: %v", err)
+		}
+		if channel >= 0 {
+			fmt.Fprintf(status, "Using only channel %d\n", channel+1)
+			convert.AudioFilter = fmt.Sprintf("pan=mono|c0=c%d", channel)
+		}
+	}
+
+	if len(selectedChannels) > 0 {
+		fmt.Fprintf(status, "Using channels %s\n", *channelsFlag)
+		convert.AudioFilter = selectChannelsFilter(selectedChannels)
+	} else if convert.AudioFilter == "" {
+		streams, err := probeAudioStreams(mediaFile)
+		if err != nil {
+			warnf("could not check the channel layout: %v", err)
+		} else if track := max(*audioTrack, 0); track < len(streams) && *titleFlag < 0 {
+			if filter, reason := downmixFilter(streams[track]); filter != "" {
+				fmt.Fprintf(status, "Audio has %d channels: %s\n", streams[track].Channels, reason)
+				convert.AudioFilter = filter
+			}
+		}
+	}
+
+	if *profileFlag != "none" {
+		profiles, err := loadProfiles()
+		if err != nil {
+			fail("Error loading profiles: %v", err)
+		}
+		name := *profileFlag
+		if name == "" {
+			name = matchProfile(profiles, videoFile)
+		}
+		if name != "" {
+			profile, ok := profiles[name]
+			if !ok {
+				fail("Error: no such profile: %s", name)
+			}
+			fmt.Fprintf(status, "Using profile %s: %s\n", name, profile.Filter)
+			convert.AudioFilter = chainFilters(convert.AudioFilter, profile.Filter)
+		}
 	}
 
-	transcriptID := transcription.ID
+	// Convert video to MP3
+	fmt.Fprintln(status, "Converting video to MP3...")
+	mp3File, err = convertToMP3(mediaFile, convert)
+	if err != nil {
+		fail("Error converting video: %v", err)
+	}
+	defer removeTemp()
 
-	// Poll for completion
-	pollingURL := fmt.Sprintf("%s/transcript/%s", assemblyAIBaseURL, transcriptID)
+	if *isolateVoiceFlag {
+		fmt.Fprintln(status, "Isolating voice...")
+		vocalsFile, err := isolateVoice(mp3File, strict)
+		if err != nil {
+			fail("Error isolating voice: %v", err)
+		}
+		if err := removeTemp(); err != nil {
+			warnf("failed to remove temporary audio: %v", err)
+		}
+		mp3File = vocalsFile
+	}
 
-	for {
-		req, err := http.NewRequest("GET", pollingURL, nil)
+	var dtmfRegions []timeRange
+	if *maskDTMFFlag {
+		fmt.Fprintln(status, "Detecting keypad tones...")
+		dtmfRegions, err = detectDTMF(mp3File)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create polling request: %w", err)
+			fail("Error detecting keypad tones: %v", err)
+		}
+		if len(dtmfRegions) > 0 {
+			maskedFile, err := maskAudio(mp3File, dtmfRegions)
+			if err != nil {
+				fail("Error masking keypad tones: %v", err)
+			}
+			if err := removeTemp(); err != nil {
+				warnf("failed to remove temporary audio: %v", err)
+			}
+			mp3File = maskedFile
 		}
+		fmt.Fprintf(status, "Masked %d keypad tone regions\n", len(dtmfRegions))
+	}
 
-		req.Header.Set("Authorization", apiKey)
+	var transcription *TranscriptionResponse
+	// remoteDeleted records that the transcript was deleted from the API, which
+	// only a fresh transcription does
+	remoteDeleted := false
+	if *fromResponse != "" {
+		fmt.Fprintf(status, "Reprocessing stored response %s\n", *fromResponse)
+		transcription, err = loadTranscription(*fromResponse)
+		if err != nil {
+			fail("Error: %v", err)
+		}
+	} else {
+		// Every billed run is recorded in the usage ledger; the budget flags only
+		// decide whether the run is refused
+		enforceBudget := *maxCost > 0 || *monthlyBudget > 0
+		var cost float64
+		ledger, err := loadLedger()
+		if err != nil {
+			if enforceBudget {
+				fail("Error loading usage ledger: %v", err)
+			}
+			warnf("failed to load usage ledger, this run is not recorded: %v", err)
+		}
+		if duration, err := probeDuration(mp3File); err == nil {
+			cost = estimateCost(duration, costliestModel(models), *pricePerHour)
+			fmt.Fprintf(status, "Estimated cost: $%.2f for %s of audio\n", cost, duration.Round(time.Second))
+		} else if enforceBudget {
+			fail("Error estimating cost: %v", err)
+		} else {
+			warnf("failed to estimate the cost, this run is not recorded: %v", err)
+			ledger = nil
+		}
+		if enforceBudget {
+			if err := checkBudget(cost, *maxCost, *monthlyBudget, ledger); err != nil {
+				fail("Error: %v", err)
+			}
+		}
 
-		resp, err := client.Do(req)
+		// Upload audio file
+		fmt.Fprintln(status, "Uploading audio file...")
+		uploadURL, err := uploadAudio(mp3File, apiKey)
 		if err != nil {
-			return nil, fmt.Errorf("failed to poll: %w", err)
+			fail("Error uploading audio: %v", err)
 		}
 
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
+		// Transcribe with diarization
+		fmt.Fprintln(status, "Transcribing audio with speaker diarization...")
+		request := transcribe.Options{
+			SpeakerLabels:    true,
+			AutoChapters:     *chapters,
+			SpeakersExpected: *speakers,
+			LanguageCode:     language,
+			WordBoost:        boostTerms,
+		}
+		if len(boostTerms) > 0 {
+			request.BoostParam = "high"
+		}
+		if policy != nil {
+			policy.applyRequest(&request)
+		}
+		var diarized bool
+		transcription, diarized, err = transcribeWithFallback(uploadURL, request, models, apiKey)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read polling response: %w", err)
+			fail("Error transcribing audio: %v", err)
+		}
+		if !diarized {
+			render.Header = append(render.Header, notDiarizedNote)
+			result.NotDiarized = true
 		}
 
-		if err := json.Unmarshal(body, &transcription); err != nil {
-			return nil, fmt.Errorf("failed to parse polling response: %w", err)
+		if ledger != nil {
+			if err := ledger.record(cost); err != nil {
+				warnf("failed to update usage ledger: %v", err)
+			}
+		}
+		if *keepRawResponses != "" {
+			body, err := fetchTranscriptRaw(transcription.ID, apiKey)
+			if err != nil {
+				fail("Error fetching raw response: %v", err)
+			}
+			rawFile, err := saveRawResponse(*keepRawResponses, transcription.ID, body)
+			if err != nil {
+				fail("Error saving raw response: %v", err)
+			}
+			fmt.Fprintf(status, "Raw response saved to: %s\n", rawFile)
+			result.Outputs = append(result.Outputs, rawFile)
 		}
 
-		switch transcription.Status {
-		case "completed":
-			return &transcription, nil
-		case "error":
-			return nil, fmt.Errorf("transcription failed: %s", transcription.Error)
-		case "queued", "processing":
-			fmt.Printf("Status: %s... waiting\n", transcription.Status)
-			time.Sleep(3 * time.Second)
-		default:
-			return nil, fmt.Errorf("unexpected status: %s", transcription.Status)
+		if strict {
+			if err := deleteTranscript(transcription.ID, apiKey); err != nil {
+				fail("Error deleting transcript from the API: %v", err)
+			}
+			remoteDeleted = true
 		}
 	}
-}
+	result.TranscriptID = transcription.ID
 
-// saveTranscription saves the transcription to a text file with speaker labels and timestamps
-func saveTranscription(filename string, transcription *TranscriptionResponse) error {
-	var output strings.Builder
+	// The API occasionally returns times past the end of the audio or out of
+	// order; repair them before anything is built on the timeline
+	audioEnd := 0
+	if duration, err := probeDuration(mp3File); err == nil {
+		audioEnd = int(duration.Milliseconds())
+	} else {
+		warnf("failed to read the audio duration, checking only the order of segments: %v", err)
+	}
+	reportRepairs(transcribe.ValidateTimes(transcription.Utterances, 0, audioEnd))
 
-	// If we have utterances with speaker info, format them nicely
 	if len(transcription.Utterances) > 0 {
-		currentSpeaker := ""
-		for _, utterance := range transcription.Utterances {
-			// Format timestamps (convert milliseconds to HH:MM:SS)
-			startTime := formatTimestamp(float64(utterance.Start) / 1000.0)
-			endTime := formatTimestamp(float64(utterance.End) / 1000.0)
-
-			speaker := utterance.Speaker
-			if speaker == "" {
-				speaker = "Unknown"
-			}
+		quality := assessDiarization(transcription, *speakers)
+		reportDiarization(quality, *speakers)
+		result.Stats.DiarizationScore = &quality.Score
+	}
+
+	if len(dtmfRegions) > 0 {
+		// Digits read out while keying may survive the masking; the sidecar must not
+		// keep them either
+		fmt.Fprintf(status, "Redacted %d segments over keypad tones\n", redactDTMF(transcription, dtmfRegions))
+	}
+
+	// Keep the transcript for the sidecar before the pipeline changes it. It is the
+	// API's response with the times repaired and the keypad tones redacted;
+	// --keep-raw-responses stores the response untouched.
+	var sidecar []byte
+	if *sidecarFlag {
+		sidecar, err = marshalTranscription(transcription)
+		if err != nil {
+			fail("Error encoding transcript: %v", err)
+		}
+	}
+
+	var reviewClusters []confidenceCluster
+	if *dialectFlag != "" {
+		reviewClusters = lowConfidenceClusters(transcription.Utterances)
+		spoken.applyGlossary(transcription)
+	}
+
+	// Resolve annotations against the segments as returned by the API
+	if *notesPath == "" {
+		*notesPath = notesFile(videoFile)
+	}
+	render.Notes, err = loadAnnotations(*notesPath, transcription.Utterances)
+	if err != nil {
+		fail("Error loading annotations: %v", err)
+	}
+	if len(render.Notes) > 0 {
+		fmt.Fprintf(status, "Loaded %d annotations from %s\n", len(render.Notes), *notesPath)
+	}
+
+	env := &pipelineEnv{
+		IgnoreRanges:   ranges,
+		IgnoreSpeakers: ignoreSpeakers,
+		Terminology:    *terminologyFile,
+		Roles:          *rolesFlag,
+		Learn:          !strict,
+		APIKey:         apiKey,
+		Render:         &render,
+	}
+	if err := runPipeline(pipeline, env, transcription); err != nil {
+		fail("Error in pipeline: %v", err)
+	}
+
+	recorded := fileDate(mediaFile)
+	if *detectDate {
+		date := detectRecordingDate(mediaFile, transcription)
+		fmt.Fprintf(status, "Recording date: %s\n", date)
+		render.Header = append(render.Header, "Recorded: "+date.String())
+		recorded = date.Date
+		result.RecordedDate = date.Date.Format("2006-01-02")
+		result.RecordedDateConfidence = date.Confidence
+	}
+
+	// Save to output files
+	outputName := strings.TrimSuffix(filepath.Base(videoFile), filepath.Ext(videoFile))
+	if *autoNameFlag {
+		outputName = autoName(recorded, transcription)
+	}
+	outputBase := filepath.Join(filepath.Dir(videoFile), outputName)
+	switch {
+	case outputTemplate != nil:
+		outputBase, err = outputBaseFromTemplate(outputTemplate, outputTemplateData{
+			Dir:  filepath.Dir(videoFile),
+			Name: outputName,
+			Date: recorded.Format("2006-01-02"),
+			ID:   transcription.ID,
+		})
+		if err != nil {
+			fail("Error: %v", err)
+		}
+	case *outputPath != "":
+		outputBase = outputBaseFromPath(*outputPath, outputName)
+	}
+	if err := os.MkdirAll(filepath.Dir(outputBase), 0755); err != nil {
+		fail("Error creating output directory: %v", err)
+	}
+
+	render.Title = *sourceTitle
+	if render.Title == "" {
+		render.Title = strings.TrimSuffix(filepath.Base(videoFile), filepath.Ext(videoFile))
+	}
+	if *detectDate {
+		render.Recorded = recorded
+	}
+	render.CitationStyle = *citationStyle
+	render.SpeakerPrefix = *subtitleSpeakers
+	render.FrameRate = *frameRate
+	render.FormatOptions = formatOptions
 
-			// Add speaker header if speaker changes
-			if speaker != currentSpeaker {
-				if currentSpeaker != "" {
-					output.WriteString("\n")
+	// writeTranscript saves the transcript and its derived outputs under outputBase
+	var transcriptFiles []string
+	writeTranscript := func(outputBase string, transcription *TranscriptionResponse) {
+		for _, name := range formats {
+			outputFile := outputBase + extensions[name]
+			var err error
+			if *toStdout {
+				if _, err := io.WriteString(os.Stdout, transcribe.Render(name, transcription, render)); err != nil {
+					fail("Error writing transcription: %v", err)
 				}
-				output.WriteString(fmt.Sprintf("[%s - %s] Speaker %s:\n", startTime, endTime, speaker))
-				currentSpeaker = speaker
+			} else if name == "dataset" {
+				fmt.Fprintln(status, "Exporting dataset clips...")
+				outputFile, err = exportDataset(outputFile, mediaFile, transcription)
+			} else if split.By != "" {
+				outputFile, err = saveSplitTranscription(outputFile, name, transcription, render, split)
 			} else {
-				output.WriteString(fmt.Sprintf("[%s - %s] ", startTime, endTime))
+				err = saveTranscription(outputFile, name, transcription, render)
+			}
+			if err != nil {
+				fail("Error saving transcription: %v", err)
+			}
+
+			if !*toStdout {
+				fmt.Fprintf(status, "Transcription saved to: %s\n", outputFile)
+				result.Outputs = append(result.Outputs, outputFile)
+				if name != "dataset" {
+					transcriptFiles = append(transcriptFiles, outputFile)
+				}
+			}
+
+			if !subtitleFormats[name] {
+				continue
+			}
+			for _, rate := range playbackRates {
+				if rate == 1 {
+					continue
+				}
+				rateFile := fmt.Sprintf("%s.%gx%s", outputBase, rate, extensions[name])
+				if err := saveTranscription(rateFile, name, transcribe.AtPlaybackRate(transcription, rate), render); err != nil {
+					fail("Error saving subtitles: %v", err)
+				}
+				fmt.Fprintf(status, "Subtitles for %gx playback saved to: %s\n", rate, rateFile)
+				result.Outputs = append(result.Outputs, rateFile)
+			}
+		}
+
+		if *exportRegions != "" {
+			regionsFile := outputBase + regionsExtension(*exportRegions)
+			if err := saveRegions(regionsFile, *exportRegions, transcription, *sampleRate, *frameRate); err != nil {
+				fail("Error exporting regions: %v", err)
+			}
+			fmt.Fprintf(status, "Regions saved to: %s\n", regionsFile)
+			result.Outputs = append(result.Outputs, regionsFile)
+		}
+
+		if *meetingReportFlag {
+			reportFile := outputBase + ".meeting.html"
+			if err := saveMeetingReport(reportFile, transcription); err != nil {
+				fail("Error writing meeting report: %v", err)
+			}
+			fmt.Fprintf(status, "Meeting report saved to: %s\n", reportFile)
+			result.Outputs = append(result.Outputs, reportFile)
+		}
+
+		if *coachFlag {
+			coachFile := outputBase + ".coach.html"
+			if err := saveCoachReport(coachFile, mp3File, transcription); err != nil {
+				fail("Error writing coaching report: %v", err)
+			}
+			fmt.Fprintf(status, "Coaching report saved to: %s\n", coachFile)
+			result.Outputs = append(result.Outputs, coachFile)
+		}
+
+		if *playerHTML {
+			playerFile := outputBase + ".player.html"
+			embed := *embedAudio
+			if downloaded && !embed {
+				// There is no media file for the page to link to
+				fmt.Fprintln(status, "Embedding the audio in the player page, since the download is not kept")
+				embed = true
+			}
+			if err := savePlayer(playerFile, videoFile, mp3File, embed, transcription, render.Styles); err != nil {
+				fail("Error writing player page: %v", err)
+			}
+			fmt.Fprintf(status, "Player page saved to: %s\n", playerFile)
+			result.Outputs = append(result.Outputs, playerFile)
+		}
+
+		if *burnSubtitlesFlag {
+			videoOutput := outputBase + ".subtitled.mp4"
+			fmt.Fprintln(status, "Burning in subtitles...")
+			if err := burnSubtitles(videoOutput, mediaFile, transcribe.Render("ass", transcription, render)); err != nil {
+				fail("Error burning in subtitles: %v", err)
 			}
+			fmt.Fprintf(status, "Subtitled video saved to: %s\n", videoOutput)
+			result.Outputs = append(result.Outputs, videoOutput)
+		}
+
+		if snippetCount > 0 {
+			snippetsDir := outputBase + "-snippets"
+			fmt.Fprintln(status, "Exporting snippets...")
+			if err := exportSnippets(snippetsDir, mp3File, transcription, snippetCount); err != nil {
+				fail("Error exporting snippets: %v", err)
+			}
+			fmt.Fprintf(status, "Snippets saved to: %s\n", filepath.Join(snippetsDir, "index.html"))
+			result.Outputs = append(result.Outputs, filepath.Join(snippetsDir, "index.html"))
+		}
+	}
 
-			output.WriteString(strings.TrimSpace(utterance.Text))
-			output.WriteString("\n")
+	var sessions []*TranscriptionResponse
+	if *autoSplitSessions {
+		sessions = splitSessions(transcription, *sessionGap)
+		fmt.Fprintf(status, "Detected %d sessions\n", len(sessions))
+	}
+	if len(sessions) > 1 {
+		used := make(map[string]bool)
+		for i, session := range sessions {
+			base := fmt.Sprintf("%s-session-%d", outputBase, i+1)
+			if *autoNameFlag {
+				base = filepath.Join(filepath.Dir(outputBase), autoName(recorded, session))
+				if used[base] {
+					base = fmt.Sprintf("%s-%d", base, i+1)
+				}
+				used[base] = true
+			}
+			writeTranscript(base, session)
 		}
 	} else {
-		// Fallback to plain text if no utterances
-		output.WriteString(transcription.Text)
-		output.WriteString("\n")
+		writeTranscript(outputBase, transcription)
+	}
+
+	if *quotesFlag {
+		fmt.Fprintln(status, "Extracting quotes...")
+		quotes, err := extractQuotes(transcription, *quotesPerSpeaker, "", apiKey)
+		if err != nil {
+			fail("Error extracting quotes: %v", err)
+		}
+		quotesFile := outputBase + ".quotes.txt"
+		if err := saveQuotes(quotesFile, quotes); err != nil {
+			fail("Error saving quotes: %v", err)
+		}
+		fmt.Fprintf(status, "Quotes saved to: %s\n", quotesFile)
+		result.Outputs = append(result.Outputs, quotesFile)
+	}
+
+	if *followUpsFlag {
+		fmt.Fprintln(status, "Extracting follow-ups...")
+		followUps, err := extractFollowUps(transcription, "", apiKey)
+		if err != nil {
+			fail("Error extracting follow-ups: %v", err)
+		}
+		followUpsFile := outputBase + ".follow-ups.txt"
+		if err := saveFollowUps(followUpsFile, followUps); err != nil {
+			fail("Error saving follow-ups: %v", err)
+		}
+		fmt.Fprintf(status, "Follow-ups saved to: %s\n", followUpsFile)
+		result.Outputs = append(result.Outputs, followUpsFile)
+	}
+
+	if sidecar != nil {
+		sidecarFile := outputBase + ".json"
+		if err := os.WriteFile(sidecarFile, sidecar, 0644); err != nil {
+			fail("Error saving transcript JSON: %v", err)
+		}
+		fmt.Fprintf(status, "Transcript JSON saved to: %s\n", sidecarFile)
+		result.Outputs = append(result.Outputs, sidecarFile)
+	}
+
+	if *dialectFlag != "" {
+		reviewFile := outputBase + ".review.txt"
+		if err := saveReview(reviewFile, *dialectFlag, reviewClusters); err != nil {
+			fail("Error writing review: %v", err)
+		}
+		fmt.Fprintf(status, "Review of %d likely misheard passages saved to: %s\n", len(reviewClusters), reviewFile)
+		result.Outputs = append(result.Outputs, reviewFile)
+	}
+
+	tags := mediaTags(filepath.Base(outputBase), strings.Join(transcriptFiles, ", "), transcription)
+
+	if *tagMediaFlag {
+		taggedFile := outputBase + ".tagged" + filepath.Ext(videoFile)
+		if err := tagMedia(mediaFile, taggedFile, tags); err != nil {
+			fail("Error tagging media: %v", err)
+		}
+		fmt.Fprintf(status, "Tagged media saved to: %s\n", taggedFile)
+		result.Outputs = append(result.Outputs, taggedFile)
+	}
+
+	if *embedChapters != "" {
+		chaptersFile := outputBase + ".chapters." + *embedChapters
+		if len(transcription.Chapters) == 0 {
+			warnf("no chapters were detected, skipping %s", chaptersFile)
+		} else if err := exportAudioWithChapters(mediaFile, chaptersFile, *embedChapters, transcription.Chapters, tags); err != nil {
+			fail("Error exporting chapters: %v", err)
+		} else {
+			fmt.Fprintf(status, "Audio with chapters saved to: %s\n", chaptersFile)
+			result.Outputs = append(result.Outputs, chaptersFile)
+		}
+	}
+
+	if strict {
+		shredded := mp3File
+		if err := removeTemp(); err != nil {
+			fail("Error shredding temporary audio: %v", err)
+		}
+		attestation := privacyAttestation(transcription.ID, remoteDeleted, shredded, result.Outputs)
+		if *toStdout {
+			// Keep the attestation out of the piped transcript
+			fmt.Fprint(status, attestation)
+		}
+		for _, transcriptFile := range transcriptFiles {
+			if err := appendToFile(trailerFile(transcriptFile), attestation); err != nil {
+				fail("Error writing privacy attestation: %v", err)
+			}
+		}
+	}
+
+	if *signKey != "" {
+		digest, err := sha256File(mediaFile)
+		if err != nil {
+			fail("Error hashing source media: %v", err)
+		}
+		for _, transcriptFile := range transcriptFiles {
+			trailer := trailerFile(transcriptFile)
+			if err := appendToFile(trailer, integrityBlock(videoFile, digest)); err != nil {
+				fail("Error writing checksum: %v", err)
+			}
+			signed := []string{transcriptFile}
+			if trailer != transcriptFile {
+				signed = append(signed, trailer)
+			}
+			for _, file := range signed {
+				signature, err := signFile(file, *signKey)
+				if err != nil {
+					fail("Error signing transcript: %v", err)
+				}
+				fmt.Fprintf(status, "Signature saved to: %s\n", signature)
+				result.Outputs = append(result.Outputs, signature)
+			}
+		}
+	}
+
+	peakSelf, peakChildren, peakKnown := peakMemory()
+	if peakKnown {
+		fmt.Fprintf(status, "Peak memory: %s (child processes: %s)\n", formatBytes(peakSelf), formatBytes(peakChildren))
+		if memoryLimit > 0 && peakSelf > memoryLimit {
+			warnf("peak memory %s exceeded --max-memory %s", formatBytes(peakSelf), formatBytes(memoryLimit))
+		}
+	}
+
+	if *resultJSON != "" {
+		result.Stats = collectStats(transcription, result.Stats.DiarizationScore)
+		result.Stats.PeakMemoryBytes, result.Stats.ChildPeakMemoryBytes = peakSelf, peakChildren
+		if err := writeResult(*resultJSON, &result); err != nil {
+			fmt.Fprintf(status, "Error writing result: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// parseArgs parses flags that may appear before, between or after positional
+// arguments and returns the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// convertOptions tweaks how the input is converted to MP3
+type convertOptions struct {
+	// AudioFilter is an FFmpeg filter graph applied to the audio
+	AudioFilter string
+	// Map selects the audio stream with an ffmpeg -map specifier
+	Map string
+	// LowPower encodes on a single thread to 16 kHz mono at a lower bitrate, which
+	// is plenty for speech and much cheaper on small devices
+	LowPower bool
+}
+
+// convertToMP3 converts a video file to MP3 format using FFmpeg
+func convertToMP3(videoFile string, opts convertOptions) (string, error) {
+	// Check if input file exists
+	if _, err := os.Stat(videoFile); os.IsNotExist(err) {
+		return "", fmt.Errorf("video file does not exist: %s", videoFile)
 	}
 
-	return os.WriteFile(filename, []byte(output.String()), 0644)
+	// Create temporary MP3 file
+	tmpFile, err := os.CreateTemp("", "transcribe-*.mp3")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpFile.Close()
+
+	mp3Path := tmpFile.Name()
+
+	// Run FFmpeg to convert video to MP3, retrying with safer strategies when the
+	// failure is one they can fix
+	var inputArgs []string
+	used := make(map[string]bool)
+	for {
+		args := append(append([]string{}, inputArgs...), "-i", videoFile, "-vn")
+		if opts.Map != "" {
+			args = append(args, "-map", opts.Map)
+		}
+		if opts.AudioFilter != "" {
+			args = append(args, "-af", opts.AudioFilter)
+		}
+		if opts.LowPower {
+			args = append(args, "-threads", "1", "-ac", "1", "-ar", "16000", "-acodec", "libmp3lame", "-q:a", "6")
+		} else {
+			args = append(args, "-acodec", "libmp3lame", "-q:a", "2")
+		}
+		args = append(args, mp3Path, "-y")
+		var stderr bytes.Buffer
+		err := retryStalled("ffmpeg", func() error {
+			stderr.Reset()
+			cmd := exec.Command("ffmpeg", args...)
+			cmd.Stderr = &stderr
+			return runWatched(cmd)
+		})
+		if err == nil {
+			break
+		}
+		retry := nextRetry(stderr.String(), used)
+		if retry == nil {
+			os.Remove(mp3Path)
+			return "", fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, stderr.String())
+		}
+		warnf("ffmpeg failed to convert %s, retrying with %s", videoFile, retry.Name)
+		used[retry.Name] = true
+		inputArgs = append(inputArgs, retry.InputArgs...)
+	}
+
+	return mp3Path, nil
+}
+
+// uploadAudio uploads an audio file and returns the URL to transcribe it from,
+// starting over when the upload stalls
+func uploadAudio(audioFile, apiKey string) (string, error) {
+	var uploadURL string
+	err := retryStalled("upload", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		var err error
+		uploadURL, err = newClient(apiKey).UploadFile(ctx, audioFile)
+		return err
+	})
+	return uploadURL, err
+}
+
+// apiURLVariable is the environment variable that overrides the API endpoint, e.g.
+// with https://api.eu.assemblyai.com/v2 or a proxy
+const apiURLVariable = "ASSEMBLYAI_API_URL"
+
+// apiURLUsage is the usage of the --api-url flag of the commands that call the API
+const apiURLUsage = "AssemblyAI API endpoint, e.g. https://api.eu.assemblyai.com/v2 (default: $" + apiURLVariable + " or " + transcribe.DefaultBaseURL + ")"
+
+// apiURL is the --api-url value of the command being run
+var apiURL string
+
+// apiBaseURL returns the API endpoint: --api-url, then the environment, then the
+// default
+func apiBaseURL() string {
+	for _, u := range []string{apiURL, os.Getenv(apiURLVariable)} {
+		if u != "" {
+			return strings.TrimSuffix(u, "/")
+		}
+	}
+	return transcribe.DefaultBaseURL
+}
+
+// defaultLemurBaseURL is the LeMUR endpoint next to the default API endpoint
+var defaultLemurBaseURL = strings.TrimSuffix(transcribe.DefaultBaseURL, "/v2") + "/lemur/v3"
+
+// lemurBaseURL returns the LeMUR endpoint, which sits next to the API endpoint
+func lemurBaseURL() string {
+	return strings.TrimSuffix(apiBaseURL(), "/v2") + "/lemur/v3"
+}
+
+// checkAPIURL returns an error if the API endpoint is not an HTTP(S) URL, or if
+// the policy does not allow it or the LeMUR endpoint next to it
+func checkAPIURL() error {
+	u, err := url.Parse(apiBaseURL())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid API endpoint: %s", apiBaseURL())
+	}
+	p, err := loadPolicy(systemPolicyFile)
+	if err != nil || p == nil {
+		return err
+	}
+	return p.checkEndpoints(apiBaseURL(), lemurBaseURL())
+}
+
+// newClient returns an API client for the configured endpoint that reports
+// polling progress on status and gives up on requests that stall
+func newClient(apiKey string) *transcribe.Client {
+	client := transcribe.NewClient(apiKey)
+	client.BaseURL = apiBaseURL()
+	client.OnStatus = func(s string) {
+		fmt.Fprintf(status, "Status: %s... waiting\n", s)
+	}
+	client.StallTimeout = stallTimeout
+	client.OnStall = func(err error) {
+		warnf("transcript poll stalled, retrying: %v", err)
+	}
+	client.OnPollError = func(err error) {
+		warnf("transcript poll failed, retrying: %v", err)
+	}
+	return client
+}
+
+// maxReportedRepairs is the number of timestamp repairs warned about one by one
+const maxReportedRepairs = 5
+
+// reportRepairs warns about repaired segment times, summarizing long lists
+func reportRepairs(repairs []string) {
+	for i, repair := range repairs {
+		if i == maxReportedRepairs {
+			warnf("repaired %d more segment times", len(repairs)-i)
+			return
+		}
+		warnf("repaired %s", repair)
+	}
+}
+
+// languageUsage is the usage of the --language flag of the commands that transcribe
+const languageUsage = "language `code` of the recording, e.g. tr or en_us, sent as a hint instead of detecting it"
+
+// languageCode matches the ISO 639 codes the API takes, with an optional region
+var languageCode = regexp.MustCompile(`^[a-z]{2,3}(_[a-z]{2})?$`)
+
+// parseLanguage normalizes a language code such as "en-US" to the API's "en_us",
+// returning "" for an empty value
+func parseLanguage(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	code := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(value)), "-", "_")
+	if !languageCode.MatchString(code) {
+		return "", fmt.Errorf("invalid language code: %s", value)
+	}
+	return code, nil
+}
+
+// notDiarizedNote heads a transcript that fell back to no speaker labels
+const notDiarizedNote = "Not diarized: speaker labels were unavailable, so segments have no speakers"
+
+const (
+	// apiRetries is how many times a rate-limited or failed API request is retried
+	// with the same model
+	apiRetries = 4
+	// apiRetryDelay is the wait before the first retry; it doubles for each one
+	apiRetryDelay = 2 * time.Second
+)
+
+// transcribeWithFallback tries each speech model in order, moving on when the API
+// rejects a model, and finally retries without speaker labels. A transcript
+// without speaker labels is segmented into sentences that have no speaker, and
+// diarized is false.
+func transcribeWithFallback(audioURL string, opts transcribe.Options, models []string, apiKey string) (transcription *TranscriptionResponse, diarized bool, err error) {
+	if len(models) == 0 {
+		// The API default model
+		models = []string{""}
+	}
+	var lastErr error
+	for i, model := range models {
+		opts.SpeechModel = model
+		transcription, err := transcribeWithRetry(audioURL, opts, apiKey)
+		if err == nil {
+			return transcription, true, nil
+		}
+		if !isModelUnavailable(err, model) {
+			return nil, false, err
+		}
+		lastErr = err
+		if i < len(models)-1 {
+			warnf("model %s unavailable (%v), trying %s", opts.SpeechModel, err, models[i+1])
+		}
+	}
+
+	warnf("diarized transcription unavailable (%v), falling back to a transcript without speakers", lastErr)
+	opts.SpeakerLabels = false
+	transcription, err = transcribeWithRetry(audioURL, opts, apiKey)
+	if err != nil {
+		return nil, false, err
+	}
+	transcription.Utterances, err = newClient(apiKey).Sentences(context.Background(), transcription.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	return transcription, false, nil
+}
+
+// transcribeWithRetry transcribes with one model, retrying a rate-limited or
+// failed request with exponential backoff. Only the submission is retried here;
+// the client retries failed polls of the submitted transcript, so that a
+// transient error never leaves a second billed transcript behind.
+func transcribeWithRetry(audioURL string, opts transcribe.Options, apiKey string) (*TranscriptionResponse, error) {
+	client := newClient(apiKey)
+	delay := apiRetryDelay
+	for attempt := 0; ; attempt++ {
+		submitted, err := client.Submit(context.Background(), audioURL, opts)
+		if err == nil {
+			return client.Wait(context.Background(), submitted.ID)
+		}
+		if !isTransient(err) || attempt == apiRetries {
+			return nil, err
+		}
+		warnf("API request failed (%v), retrying in %s", err, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
 }
 
-// formatTimestamp converts seconds to HH:MM:SS format
-func formatTimestamp(seconds float64) string {
-	duration := time.Duration(seconds * float64(time.Second))
-	hours := int(duration.Hours())
-	minutes := int(duration.Minutes()) % 60
-	secs := int(duration.Seconds()) % 60
-	return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, secs)
+// isTransient reports whether err is a rate limit or server error that the same
+// request may not meet again
+func isTransient(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Temporary()
+}
+
+// isModelUnavailable reports whether err is the API refusing model, which another
+// model may not be: the model is forbidden or unknown, or a bad request names it
+func isModelUnavailable(err error, model string) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusForbidden, http.StatusNotFound:
+		return true
+	case http.StatusBadRequest:
+		return strings.Contains(apiErr.Body, "speech_model") || (model != "" && strings.Contains(apiErr.Body, model))
+	}
+	return false
+}
+
+// transcribeAudio submits audio for transcription and polls until complete
+func transcribeAudio(audioURL string, opts transcribe.Options, apiKey string) (*TranscriptionResponse, error) {
+	return newClient(apiKey).Transcribe(context.Background(), audioURL, opts)
+}
+
+// saveTranscription saves the transcription to a file in the given output format
+func saveTranscription(filename, format string, transcription *TranscriptionResponse, opts transcribe.RenderOptions) error {
+	return os.WriteFile(filename, []byte(transcribe.Render(format, transcription, opts)), 0644)
+}
+
+// loadTranscription reads a transcript JSON document as returned by the API,
+// optionally gzipped
+func loadTranscription(filename string) (*TranscriptionResponse, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	// Raw responses kept with --keep-raw-responses are gzipped
+	data, err = decompressIfGzip(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress transcript: %w", err)
+	}
+
+	var transcription TranscriptionResponse
+	if err := json.Unmarshal(data, &transcription); err != nil {
+		return nil, fmt.Errorf("failed to parse transcript: %w", err)
+	}
+
+	return &transcription, nil
+}
+
+// marshalTranscription encodes a transcript in the JSON document format read by
+// loadTranscription
+func marshalTranscription(transcription *TranscriptionResponse) ([]byte, error) {
+	data, err := json.MarshalIndent(transcription, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// chapterAudioCodecs maps the supported chapter output formats to their audio codec
+var chapterAudioCodecs = map[string]string{
+	"m4b": "aac",
+	"mp3": "libmp3lame",
+}
+
+// escapeFFMetadata escapes the characters that are special in FFmpeg metadata files
+func escapeFFMetadata(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
+	return replacer.Replace(s)
+}
+
+// ffMetadata renders chapters in FFmpeg's metadata file format
+func ffMetadata(chapters []Chapter) string {
+	var output strings.Builder
+	output.WriteString(";FFMETADATA1\n")
+	for _, chapter := range chapters {
+		output.WriteString("[CHAPTER]\nTIMEBASE=1/1000\n")
+		output.WriteString(fmt.Sprintf("START=%d\nEND=%d\n", chapter.Start, chapter.End))
+		output.WriteString(fmt.Sprintf("title=%s\n", escapeFFMetadata(chapter.Headline)))
+	}
+	return output.String()
+}
+
+// mediaTags returns the metadata that ties a media file to its transcript
+func mediaTags(title, transcriptFile string, transcription *TranscriptionResponse) map[string]string {
+	tags := map[string]string{
+		"title":   title,
+		"comment": fmt.Sprintf("transcript %s (%s)", transcription.ID, transcriptFile),
+	}
+	if transcription.LanguageCode != "" {
+		tags["language"] = transcription.LanguageCode
+	}
+
+	var summary []string
+	for _, chapter := range transcription.Chapters {
+		summary = append(summary, chapter.Gist)
+	}
+	if len(summary) > 0 {
+		tags["description"] = strings.Join(summary, "; ")
+	}
+	return tags
+}
+
+// metadataArgs returns the FFmpeg arguments that set the given tags
+func metadataArgs(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var args []string
+	for _, key := range keys {
+		args = append(args, "-metadata", key+"="+tags[key])
+	}
+	return args
+}
+
+// tagMedia writes a copy of mediaFile with the given metadata tags, without
+// re-encoding any streams
+func tagMedia(mediaFile, outFile string, tags map[string]string) error {
+	args := []string{"-i", mediaFile, "-map", "0", "-c", "copy"}
+	args = append(args, metadataArgs(tags)...)
+	args = append(args, outFile, "-y")
+	return runFFmpeg(args...)
+}
+
+// runFFmpeg runs FFmpeg with args, including its output in the error on failure.
+// A run that stalls is killed and retried.
+func runFFmpeg(args ...string) error {
+	return retryStalled("ffmpeg", func() error {
+		cmd := exec.Command("ffmpeg", args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		if err := runWatched(cmd); err != nil {
+			return fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, stderr.String())
+		}
+		return nil
+	})
+}
+
+// exportAudioWithChapters writes the audio of mediaFile to outFile with the chapters
+// embedded as chapter metadata, in the given format ("m4b" or "mp3")
+func exportAudioWithChapters(mediaFile, outFile, format string, chapters []Chapter, tags map[string]string) error {
+	codec, ok := chapterAudioCodecs[format]
+	if !ok {
+		return fmt.Errorf("unsupported chapter audio format: %s", format)
+	}
+
+	metaFile, err := os.CreateTemp("", "transcribe-*.ffmeta")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(metaFile.Name())
+	if _, err := metaFile.WriteString(ffMetadata(chapters)); err != nil {
+		metaFile.Close()
+		return err
+	}
+	if err := metaFile.Close(); err != nil {
+		return err
+	}
+
+	args := []string{"-i", mediaFile, "-i", metaFile.Name(), "-map", "0:a:0", "-map_metadata", "1", "-map_chapters", "1", "-acodec", codec}
+	if format == "mp3" {
+		args = append(args, "-q:a", "2", "-id3v2_version", "3")
+	} else {
+		args = append(args, "-f", "ipod")
+	}
+	args = append(args, metadataArgs(tags)...)
+	args = append(args, outFile, "-y")
+	return runFFmpeg(args...)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"transcribe/pkg/transcribe"
+)
+
+const (
+	// interruptionGapMs is the longest pause after an unfinished sentence that still
+	// counts as the next speaker cutting in
+	interruptionGapMs = 300
+	// reportMonologues is the number of longest monologues listed in the report
+	reportMonologues = 5
+)
+
+// speakerTalk holds per-speaker figures of the meeting report
+type speakerTalk struct {
+	Speaker   string
+	TalkTime  time.Duration
+	Share     float64
+	Turns     int
+	Questions int
+}
+
+// monologue is an uninterrupted run of speech by one speaker
+type monologue struct {
+	Speaker  string
+	Start    string
+	Duration time.Duration
+	Excerpt  string
+}
+
+// meetingReport holds the figures rendered on the meeting-health dashboard
+type meetingReport struct {
+	Speakers []speakerTalk
+	// Interruptions[i][j] is how often Speakers[i] interrupted Speakers[j]
+	Interruptions [][]int
+	Monologues    []monologue
+}
+
+var meetingReportPage = template.Must(template.New("meeting").Funcs(template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+	"round":   func(d time.Duration) time.Duration { return d.Round(time.Second) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Meeting report</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.8em; text-align: left; }
+.bar { background: #1f77b4; height: 1em; }
+</style>
+</head>
+<body>
+<h1>Meeting report</h1>
+<h2>Talk time</h2>
+<table>
+<tr><th>Speaker</th><th>Talk time</th><th>Share</th><th></th><th>Turns</th><th>Questions</th></tr>
+{{range .Speakers}}<tr><td>{{.Speaker}}</td><td>{{round .TalkTime}}</td><td>{{percent .Share}}</td><td style="width: 20em"><div class="bar" style="width: {{percent .Share}}"></div></td><td>{{.Turns}}</td><td>{{.Questions}}</td></tr>
+{{end}}</table>
+<h2>Interruptions</h2>
+<p>Rows interrupted columns.</p>
+<table>
+<tr><th></th>{{range .Speakers}}<th>{{.Speaker}}</th>{{end}}</tr>
+{{range $i, $row := .Interruptions}}<tr><th>{{(index $.Speakers $i).Speaker}}</th>{{range $row}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+<h2>Longest monologues</h2>
+<table>
+<tr><th>Speaker</th><th>Start</th><th>Duration</th><th>Excerpt</th></tr>
+{{range .Monologues}}<tr><td>{{.Speaker}}</td><td>{{.Start}}</td><td>{{round .Duration}}</td><td>{{.Excerpt}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// buildMeetingReport computes talk-time balance, questions, interruptions and the
+// longest monologues from the utterances
+func buildMeetingReport(utterances []Utterance) meetingReport {
+	var report meetingReport
+	index := make(map[string]int)
+	for _, utterance := range utterances {
+		if _, ok := index[utterance.Speaker]; !ok {
+			index[utterance.Speaker] = len(report.Speakers)
+			report.Speakers = append(report.Speakers, speakerTalk{Speaker: utterance.Speaker})
+		}
+	}
+
+	report.Interruptions = make([][]int, len(report.Speakers))
+	for i := range report.Interruptions {
+		report.Interruptions[i] = make([]int, len(report.Speakers))
+	}
+
+	var total time.Duration
+	var monologues []monologue
+	for i, utterance := range utterances {
+		talk := &report.Speakers[index[utterance.Speaker]]
+		duration := time.Duration(utterance.End-utterance.Start) * time.Millisecond
+		talk.TalkTime += duration
+		talk.Questions += strings.Count(utterance.Text, "?")
+		total += duration
+
+		if i > 0 && utterances[i-1].Speaker == utterance.Speaker {
+			// Consecutive utterances of the same speaker form one monologue
+			last := &monologues[len(monologues)-1]
+			last.Duration += time.Duration(utterance.End-utterances[i-1].End) * time.Millisecond
+			continue
+		}
+
+		talk.Turns++
+		monologues = append(monologues, monologue{
+			Speaker:  utterance.Speaker,
+			Start:    transcribe.FormatTimestamp(float64(utterance.Start) / 1000.0),
+			Duration: duration,
+			Excerpt:  transcribe.Excerpt(utterance.Text, 20),
+		})
+
+		if i > 0 && isInterruption(utterances[i-1], utterance) {
+			report.Interruptions[index[utterance.Speaker]][index[utterances[i-1].Speaker]]++
+		}
+	}
+
+	for i := range report.Speakers {
+		if total > 0 {
+			report.Speakers[i].Share = float64(report.Speakers[i].TalkTime) / float64(total)
+		}
+	}
+
+	slices.SortStableFunc(monologues, func(a, b monologue) int { return int(b.Duration - a.Duration) })
+	report.Monologues = monologues[:min(reportMonologues, len(monologues))]
+	return report
+}
+
+// isInterruption reports whether next cut in on previous: it started before previous
+// ended, or right after previous stopped mid-sentence
+func isInterruption(previous, next Utterance) bool {
+	if next.Start < previous.End {
+		return true
+	}
+	return !transcribe.EndsSentence(previous.Text) && next.Start-previous.End <= interruptionGapMs
+}
+
+// saveMeetingReport renders the meeting report of the transcription as HTML
+func saveMeetingReport(filename string, transcription *TranscriptionResponse) error {
+	if len(transcription.Utterances) == 0 {
+		return fmt.Errorf("transcript has no speaker segments")
+	}
+
+	var page bytes.Buffer
+	if err := meetingReportPage.Execute(&page, buildMeetingReport(transcription.Utterances)); err != nil {
+		return fmt.Errorf("failed to render meeting report: %w", err)
+	}
+	return os.WriteFile(filename, page.Bytes(), 0644)
+}
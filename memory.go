@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// memoryUnits maps size suffixes to their multiplier
+var memoryUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3},
+	{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// parseMemorySize parses sizes like 512M, 2GiB or 1500000000
+func parseMemorySize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	multiplier := int64(1)
+	for _, unit := range memoryUnits {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			value = strings.TrimSpace(number)
+			multiplier = unit.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid memory size: %s", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// setMemoryLimit makes the garbage collector work harder as the process nears
+// limit so that the heap stays below it
+func setMemoryLimit(limit int64) {
+	debug.SetMemoryLimit(limit)
+}
+
+// formatBytes formats a byte count in MiB
+func formatBytes(n int64) string {
+	return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+}
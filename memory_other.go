@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// peakMemory is not available on this platform
+func peakMemory() (self, children int64, ok bool) {
+	return 0, 0, false
+}
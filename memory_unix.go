@@ -0,0 +1,23 @@
+//go:build unix
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+)
+
+// peakMemory returns the peak resident set size in bytes of this process and of
+// the largest child process it waited for, such as ffmpeg
+func peakMemory() (self, children int64, ok bool) {
+	var selfUsage, childUsage syscall.Rusage
+	if syscall.Getrusage(syscall.RUSAGE_SELF, &selfUsage) != nil || syscall.Getrusage(syscall.RUSAGE_CHILDREN, &childUsage) != nil {
+		return 0, 0, false
+	}
+	// Maxrss is reported in bytes on macOS and in kilobytes elsewhere
+	scale := int64(1024)
+	if runtime.GOOS == "darwin" {
+		scale = 1
+	}
+	return int64(selfUsage.Maxrss) * scale, int64(childUsage.Maxrss) * scale, true
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"transcribe/pkg/transcribe"
+
+	"gopkg.in/yaml.v3"
+)
+
+// notesFile returns the sidecar annotations file for an input file
+func notesFile(videoFile string) string {
+	return strings.TrimSuffix(videoFile, filepath.Ext(videoFile)) + ".notes.yaml"
+}
+
+// loadAnnotations reads a sidecar annotations file and resolves each entry to a
+// position in the recording. A missing file yields no annotations.
+func loadAnnotations(filename string, utterances []Utterance) ([]transcribe.Annotation, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read annotations: %w", err)
+	}
+
+	var annotations []transcribe.Annotation
+	if err := yaml.Unmarshal(data, &annotations); err != nil {
+		return nil, fmt.Errorf("failed to parse annotations: %w", err)
+	}
+
+	for i := range annotations {
+		a := &annotations[i]
+		switch {
+		case a.Segment > 0:
+			if a.Segment > len(utterances) {
+				return nil, fmt.Errorf("annotation %d refers to segment %d, but the transcript has %d", i+1, a.Segment, len(utterances))
+			}
+			a.Position = utterances[a.Segment-1].Start
+		case a.At != "":
+			at, err := parseTimestamp(a.At)
+			if err != nil {
+				return nil, fmt.Errorf("annotation %d: %w", i+1, err)
+			}
+			a.Position = int(at.Milliseconds())
+		default:
+			return nil, fmt.Errorf("annotation %d has neither segment nor at", i+1)
+		}
+	}
+
+	return annotations, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// extPlaceholder stands in for {{.Ext}} while the output template is rendered,
+// since each output format appends its own extension
+const extPlaceholder = "\x00ext\x00"
+
+// outputTemplateData holds the fields available to --output-template
+type outputTemplateData struct {
+	// Dir is the directory of the input file
+	Dir string
+	// Name is the input file name without its extension, or the --auto-name name
+	Name string
+	// Date is the recording date as YYYY-MM-DD
+	Date string
+	// ID is the AssemblyAI transcript ID
+	ID string
+	// Ext is the extension of each output, without the leading dot
+	Ext string
+}
+
+// parseOutputTemplate parses an --output-template value and renders it once with
+// sample data, so that mistakes surface before the audio is uploaded
+func parseOutputTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse output template: %w", err)
+	}
+	if _, err := outputBaseFromTemplate(tmpl, outputTemplateData{Dir: ".", Name: "name", Date: "2006-01-02", ID: "id"}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// outputBaseFromTemplate renders the output template into the path that output
+// extensions are appended to. {{.Ext}} may only appear at the end, after a dot,
+// because every format supplies its own extension.
+func outputBaseFromTemplate(tmpl *template.Template, data outputTemplateData) (string, error) {
+	data.Ext = extPlaceholder
+	var output strings.Builder
+	if err := tmpl.Execute(&output, data); err != nil {
+		return "", fmt.Errorf("failed to render output template: %w", err)
+	}
+
+	base := output.String()
+	if strings.Contains(base, extPlaceholder) {
+		if !strings.HasSuffix(base, "."+extPlaceholder) || strings.Count(base, extPlaceholder) > 1 {
+			return "", fmt.Errorf("{{.Ext}} must come last in the output template, after a dot")
+		}
+		base = strings.TrimSuffix(base, "."+extPlaceholder)
+	}
+	if base == "" {
+		return "", fmt.Errorf("output template renders an empty path")
+	}
+	return filepath.Clean(base), nil
+}
+
+// outputBaseFromPath returns the output base for an --output value: a directory
+// (an existing one, or any path ending in a separator) receives the outputs under
+// name, any other path has its extension replaced by those of the formats
+func outputBaseFromPath(path, name string) string {
+	if strings.HasSuffix(path, string(filepath.Separator)) || strings.HasSuffix(path, "/") {
+		return filepath.Join(path, name)
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return filepath.Join(path, name)
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
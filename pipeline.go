@@ -0,0 +1,457 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"transcribe/pkg/transcribe"
+)
+
+// defaultPipeline runs the stages configured by their own flags, in the order
+// they ran before --pipeline existed
+const defaultPipeline = "ignore,terminology,roles"
+
+// translateBatch is the number of segments translated per LeMUR request
+const translateBatch = 50
+
+// pipelineStage describes a --pipeline stage
+type pipelineStage struct {
+	// Arg names the value after the colon, e.g. LANG in translate:LANG; empty for
+	// stages that take none
+	Arg string
+	// ArgRequired stages fail without a value
+	ArgRequired bool
+	Usage       string
+	// LeMUR stages send the transcript text to LeMUR
+	LeMUR bool
+	Run   func(env *pipelineEnv, transcription *TranscriptionResponse, arg string) error
+}
+
+// pipelineStep is a stage of a parsed pipeline
+type pipelineStep struct {
+	Name string
+	Arg  string
+}
+
+// pipelineEnv holds what the stages take from the rest of the run
+type pipelineEnv struct {
+	IgnoreRanges   []timeRange
+	IgnoreSpeakers []string
+	// Terminology and Roles are the --terminology and --roles values, used by their
+	// stages when the step gives no value
+	Terminology string
+	Roles       string
+	// Learn adds new terms to the terminology memory
+	Learn  bool
+	APIKey string
+	// Render receives the header lines added by stages such as summarize
+	Render *transcribe.RenderOptions
+}
+
+// pipelineStages is the registry of --pipeline stages. It is filled in init
+// because the usage message lists it.
+var pipelineStages map[string]pipelineStage
+
+func init() {
+	pipelineStages = map[string]pipelineStage{
+		"ignore": {
+			Usage: "drop the segments selected by --ignore-range and --ignore-speaker",
+			Run:   ignoreStage,
+		},
+		"terminology": {
+			Arg:   "FILE",
+			Usage: "apply a terminology memory (default: --terminology)",
+			Run:   terminologyStage,
+		},
+		"roles": {
+			Arg:   "SET",
+			Usage: "label speakers with their role (default: --roles)",
+			Run:   rolesStage,
+		},
+		"smooth": {
+			Arg:   "GAP",
+			Usage: "relabel short segments inside another speaker's turn and merge segments of a speaker less than GAP apart (default 1s)",
+			Run:   smoothStage,
+		},
+		"redact": {
+			Arg:   "TYPES",
+			Usage: "replace emails, phone, card and social security numbers in the text; TYPES limits it, e.g. email_address+phone_number",
+			Run:   redactStage,
+		},
+		"normalize": {
+			Usage: "remove hesitations of the transcript's language such as um and uh, repeated words and extra whitespace",
+			Run:   normalizeStage,
+		},
+		"translate": {
+			Arg:         "LANG",
+			ArgRequired: true,
+			Usage:       "translate the segments with LeMUR, e.g. translate:de",
+			LeMUR:       true,
+			Run:         translateStage,
+		},
+		"summarize": {
+			Usage: "add a summary written by LeMUR to the header of the transcript",
+			LeMUR: true,
+			Run:   summarizeStage,
+		},
+	}
+}
+
+// pipelineUsage lists the stages for the --pipeline flag
+func pipelineUsage() string {
+	var usage strings.Builder
+	usage.WriteString("comma-separated post-processing stages, run in order (default " + defaultPipeline + "):")
+	for _, name := range slices.Sorted(maps.Keys(pipelineStages)) {
+		stage := pipelineStages[name]
+		switch {
+		case stage.ArgRequired:
+			name += ":" + stage.Arg
+		case stage.Arg != "":
+			name += "[:" + stage.Arg + "]"
+		}
+		usage.WriteString("\n" + name + ": " + stage.Usage)
+	}
+	return usage.String()
+}
+
+// parsePipeline parses a --pipeline value such as "smooth,redact,translate:de"
+func parsePipeline(value string) ([]pipelineStep, error) {
+	var steps []pipelineStep
+	for _, text := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(text), ":")
+		stage, ok := pipelineStages[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown pipeline stage: %s", name)
+		}
+		if arg != "" && stage.Arg == "" {
+			return nil, fmt.Errorf("pipeline stage %s takes no value", name)
+		}
+		if arg == "" && stage.ArgRequired {
+			return nil, fmt.Errorf("pipeline stage %s needs a value: %s:%s", name, name, stage.Arg)
+		}
+		if err := checkStageArg(name, arg); err != nil {
+			return nil, err
+		}
+		steps = append(steps, pipelineStep{Name: name, Arg: arg})
+	}
+	return steps, nil
+}
+
+// checkStageArg validates the value of a step before the run is paid for
+func checkStageArg(name, arg string) error {
+	if arg == "" {
+		return nil
+	}
+	switch name {
+	case "smooth":
+		if gap, err := time.ParseDuration(arg); err != nil || gap < 0 {
+			return fmt.Errorf("invalid smoothing gap: %s", arg)
+		}
+	case "roles":
+		if _, ok := roleSets[arg]; !ok && arg != "auto" {
+			return fmt.Errorf("unknown role set: %s", arg)
+		}
+	case "redact":
+		for _, entity := range strings.Split(arg, "+") {
+			if !slices.ContainsFunc(redactPatterns, func(p redactPattern) bool { return p.Entity == entity }) {
+				return fmt.Errorf("unknown redaction type: %s", entity)
+			}
+		}
+	}
+	return nil
+}
+
+// hasStep reports whether the pipeline runs the stage
+func hasStep(steps []pipelineStep, name string) bool {
+	return slices.ContainsFunc(steps, func(step pipelineStep) bool { return step.Name == name })
+}
+
+// usesLeMUR reports whether the pipeline sends the transcript to LeMUR
+func usesLeMUR(steps []pipelineStep) bool {
+	return slices.ContainsFunc(steps, func(step pipelineStep) bool { return pipelineStages[step.Name].LeMUR })
+}
+
+// runPipeline runs the steps in order on the transcription
+func runPipeline(steps []pipelineStep, env *pipelineEnv, transcription *TranscriptionResponse) error {
+	for _, step := range steps {
+		before := slices.Clone(transcription.Utterances)
+		if err := pipelineStages[step.Name].Run(env, transcription, step.Arg); err != nil {
+			return fmt.Errorf("%s: %w", step.Name, err)
+		}
+		if !slices.Equal(before, transcription.Utterances) {
+			transcription.Text = transcribe.TranscriptText(transcription)
+		}
+	}
+	return nil
+}
+
+// ignoreStage drops the segments selected by the ignore flags
+func ignoreStage(env *pipelineEnv, transcription *TranscriptionResponse, _ string) error {
+	if len(env.IgnoreRanges) == 0 && len(env.IgnoreSpeakers) == 0 {
+		return nil
+	}
+	removed := ignoreSegments(transcription, env.IgnoreRanges, env.IgnoreSpeakers)
+	fmt.Fprintf(status, "Ignored %d segments\n", removed)
+	return nil
+}
+
+// terminologyStage applies the terminology memory given to the step or --terminology
+func terminologyStage(env *pipelineEnv, transcription *TranscriptionResponse, file string) error {
+	if file == "" {
+		file = env.Terminology
+	}
+	if file == "" {
+		return nil
+	}
+	return enforceTerminology(file, transcription, env.Learn)
+}
+
+// rolesStage labels speakers with the role set given to the step or --roles
+func rolesStage(env *pipelineEnv, transcription *TranscriptionResponse, kind string) error {
+	if kind == "" {
+		kind = env.Roles
+	}
+	if kind == "" {
+		return nil
+	}
+	roles := classifyRoles(transcription.Utterances, kind)
+	if roles == nil {
+		warnf("roles need at least two speakers, leaving speaker labels unchanged")
+		return nil
+	}
+	fmt.Fprintf(status, "Speaker roles: %s\n", describeRoles(transcription.Utterances, roles))
+	applyRoles(transcription, roles)
+	return nil
+}
+
+// smoothStage evens out jittery diarization
+func smoothStage(_ *pipelineEnv, transcription *TranscriptionResponse, arg string) error {
+	gap := time.Second
+	if arg != "" {
+		gap, _ = time.ParseDuration(arg)
+	}
+	var relabeled, merged int
+	transcription.Utterances, relabeled, merged = smoothSegments(transcription.Utterances, gap)
+	fmt.Fprintf(status, "Smoothed segments: %d relabeled, %d merged\n", relabeled, merged)
+	return nil
+}
+
+// smoothSegments gives segments shorter than shortSegmentMs that interrupt a
+// single speaker to that speaker, then merges consecutive segments of a speaker
+// that are at most gap apart. It returns the segments and the number of segments
+// relabeled and merged away.
+func smoothSegments(utterances []Utterance, gap time.Duration) ([]Utterance, int, int) {
+	relabeled := 0
+	for i := 1; i+1 < len(utterances); i++ {
+		u := &utterances[i]
+		if u.End-u.Start < shortSegmentMs && u.Speaker != utterances[i-1].Speaker && utterances[i-1].Speaker == utterances[i+1].Speaker {
+			u.Speaker = utterances[i-1].Speaker
+			relabeled++
+		}
+	}
+
+	var smoothed []Utterance
+	for _, utterance := range utterances {
+		if n := len(smoothed); n > 0 {
+			last := &smoothed[n-1]
+			if last.Speaker == utterance.Speaker && time.Duration(utterance.Start-last.End)*time.Millisecond <= gap {
+				// Weight the confidence by the length of the parts
+				a, b := float64(last.End-last.Start), float64(utterance.End-utterance.Start)
+				if a+b > 0 {
+					last.Confidence = (last.Confidence*a + utterance.Confidence*b) / (a + b)
+				}
+				last.End = max(last.End, utterance.End)
+				last.Text = transcribe.JoinSentences(strings.TrimSpace(last.Text), strings.TrimSpace(utterance.Text))
+				continue
+			}
+		}
+		smoothed = append(smoothed, utterance)
+	}
+	return smoothed, relabeled, len(utterances) - len(smoothed)
+}
+
+// redactPattern finds an entity type in the text. Entity names follow the API's
+// PII policies.
+type redactPattern struct {
+	Entity  string
+	Pattern *regexp.Regexp
+}
+
+// redactPatterns are tried in order, so that card numbers are not taken for phone
+// numbers. Names cannot be found by pattern and are left to the API's --redact-pii.
+var redactPatterns = []redactPattern{
+	{"us_social_security_number", regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{"credit_card_number", regexp.MustCompile(`\b(?:\d[ -]?){12,15}\d\b`)},
+	{"email_address", regexp.MustCompile(`[\w.+-]+@[\w-]+(?:\.[\w-]+)+`)},
+	// A phone number is grouped 3-3-4 as in (555) 123-4567, or has a country code
+	// and groups of digits as in +44 20 7946 0958; dates and times are neither
+	{"phone_number", regexp.MustCompile(`(?:(?:\+\d{1,3}[ .-]?)?\(\d{3}\)[ .-]?|(?:\+\d{1,3}[ .-]?|\b)\d{3}[ .-]?)\d{3}[ .-]?\d{4}\b|\+\d{1,3}(?:[ .-]?\d{2,4}){3,5}\b`)},
+}
+
+// redactStage replaces the entities in the segment text with their type, as the
+// API does with the entity_name substitution
+func redactStage(_ *pipelineEnv, transcription *TranscriptionResponse, arg string) error {
+	var entities []string
+	if arg != "" {
+		entities = strings.Split(arg, "+")
+	}
+	count := 0
+	for i := range transcription.Utterances {
+		text := transcription.Utterances[i].Text
+		for _, p := range redactPatterns {
+			if entities != nil && !slices.Contains(entities, p.Entity) {
+				continue
+			}
+			text = p.Pattern.ReplaceAllStringFunc(text, func(string) string {
+				count++
+				return "[" + strings.ToUpper(p.Entity) + "]"
+			})
+		}
+		transcription.Utterances[i].Text = text
+	}
+	fmt.Fprintf(status, "Redacted %d items\n", count)
+	return nil
+}
+
+// hesitations are the fillers removed by the normalize stage, by language. Unlike
+// the words of fillerWords they carry no meaning in any context of the language;
+// "er" is a hesitation in English but a word in Turkish.
+var hesitations = map[string][]string{
+	"en": {"um", "uh", "er", "erm", "ah", "hmm", "mm"},
+	"de": {"äh", "ähm", "öh", "öhm", "hm", "hmm"},
+	"fr": {"euh", "heu", "hum", "hmm"},
+	"es": {"eh", "em", "ehm", "mmm"},
+	"tr": {"ıı", "ııı", "ee", "eee", "hım", "hmm"},
+}
+
+// normalizeStage cleans up the text of every segment, removing the hesitations
+// of the transcript's language. Without a known language only repeated words and
+// whitespace are cleaned up.
+func normalizeStage(_ *pipelineEnv, transcription *TranscriptionResponse, _ string) error {
+	language, _, _ := strings.Cut(transcription.LanguageCode, "_")
+	fillers := hesitations[strings.ToLower(language)]
+	for i := range transcription.Utterances {
+		transcription.Utterances[i].Text = normalizeText(transcription.Utterances[i].Text, fillers)
+	}
+	transcription.Utterances = slices.DeleteFunc(transcription.Utterances, func(utterance Utterance) bool {
+		return utterance.Text == ""
+	})
+	return nil
+}
+
+// normalizeText removes the fillers and immediately repeated words and collapses
+// whitespace. Sentence punctuation of a removed word moves to the word before it,
+// and a sentence that started with one starts with the next word capitalized.
+func normalizeText(text string, fillers []string) string {
+	var words []string
+	capitalize := false
+	for _, token := range strings.Fields(text) {
+		word := strings.TrimRight(token, ",.?!;:")
+		punctuation := token[len(word):]
+		lower := strings.ToLower(word)
+
+		var previous string
+		if len(words) > 0 {
+			previous = words[len(words)-1]
+		}
+		// "the the" and "I, I" are stutters; "2 2" and "done. Done" are not
+		repeated := lower != "" && !unicode.IsDigit(firstRune(word)) &&
+			strings.ToLower(strings.TrimRight(previous, ",")) == lower
+		if !slices.Contains(fillers, lower) && !repeated {
+			if capitalize {
+				r, size := utf8.DecodeRuneInString(token)
+				token = string(unicode.ToUpper(r)) + token[size:]
+				capitalize = false
+			}
+			words = append(words, token)
+			continue
+		}
+
+		switch {
+		case repeated:
+			words[len(words)-1] = strings.TrimRight(previous, ",") + punctuation
+		case previous == "" || transcribe.EndsSentence(previous):
+			capitalize = capitalize || word != lower
+		case strings.ContainsAny(punctuation, ".?!"):
+			words[len(words)-1] = strings.TrimRight(previous, ",;:") + punctuation
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// firstRune returns the first rune of s, or zero for an empty string
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+const translatePrompt = `Translate each string of the JSON array given as input into the language %s.
+Keep names, numbers and technical terms as they are.
+Respond with only a JSON array of the translated strings, in the same order and with
+exactly as many elements as the input.`
+
+// translateStage translates the segment text with LeMUR in batches and sets the
+// language of the transcript to the target
+func translateStage(env *pipelineEnv, transcription *TranscriptionResponse, lang string) error {
+	if env.APIKey == "" {
+		return errors.New("translation needs an AssemblyAI API key")
+	}
+	utterances := transcription.Utterances
+	for start := 0; start < len(utterances); start += translateBatch {
+		batch := utterances[start:min(start+translateBatch, len(utterances))]
+		texts := make([]string, len(batch))
+		for i, utterance := range batch {
+			texts[i] = utterance.Text
+		}
+		input, err := json.Marshal(texts)
+		if err != nil {
+			return err
+		}
+
+		answer, err := runLemurText(string(input), fmt.Sprintf(translatePrompt, lang), "", env.APIKey)
+		if err != nil {
+			return err
+		}
+		var translated []string
+		if err := parseLemurJSON(answer, &translated); err != nil {
+			return err
+		}
+		if len(translated) != len(batch) {
+			return fmt.Errorf("model returned %d translations for %d segments", len(translated), len(batch))
+		}
+		for i := range batch {
+			batch[i].Text = strings.TrimSpace(translated[i])
+		}
+		fmt.Fprintf(status, "Translated %d of %d segments\n", start+len(batch), len(utterances))
+	}
+	transcription.LanguageCode = lang
+	return nil
+}
+
+// summarizeStage asks LeMUR to summarize the transcript as it stands at this point
+// of the pipeline and adds the summary to the header
+func summarizeStage(env *pipelineEnv, transcription *TranscriptionResponse, _ string) error {
+	if env.APIKey == "" {
+		return errors.New("summarizing needs an AssemblyAI API key")
+	}
+	var input strings.Builder
+	for _, utterance := range transcription.Utterances {
+		input.WriteString(fmt.Sprintf("Speaker %s: %s\n", utterance.Speaker, strings.TrimSpace(utterance.Text)))
+	}
+	summary, err := runLemurText(input.String(), summaryPrompt, "", env.APIKey)
+	if err != nil {
+		return err
+	}
+	env.Render.Header = append(env.Render.Header, "Summary:")
+	env.Render.Header = append(env.Render.Header, strings.Split(strings.TrimSpace(summary), "\n")...)
+	fmt.Fprintln(status, "Summary added to the transcript header")
+	return nil
+}
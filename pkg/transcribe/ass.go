@@ -0,0 +1,81 @@
+package transcribe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// assHeader sets up a 1080p script; caption sizes and margins are relative to it
+const assHeader = `[Script Info]
+ScriptType: v4.00+
+PlayResX: 1920
+PlayResY: 1080
+WrapStyle: 2
+ScaledBorderAndShadow: yes
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+`
+
+// assEscaper keeps caption text from being read as override blocks
+var assEscaper = strings.NewReplacer("{", "(", "}", ")", "\\", "/", "\n", " ")
+
+// assAlignment maps a position to the numeric keypad alignment of ASS
+var assAlignment = map[string]int{PositionBottom: 2, PositionMiddle: 5, PositionTop: 8}
+
+// assColor converts a #rrggbb color to the &HAABBGGRR form of ASS
+func assColor(color, fallback string) string {
+	rgb, err := strconv.ParseUint(strings.TrimPrefix(color, "#"), 16, 32)
+	if err != nil || len(color) != 7 {
+		return fallback
+	}
+	return fmt.Sprintf("&H00%02X%02X%02X", rgb&0xff, rgb>>8&0xff, rgb>>16)
+}
+
+// assTimestamp formats ms as the H:MM:SS.cc time of ASS
+func assTimestamp(ms int) string {
+	cs := ms / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
+
+// assStyle formats a style line of the [V4+ Styles] section
+func assStyle(name string, style Style) string {
+	font := style.Font
+	if font == "" {
+		font = "Arial"
+	}
+	alignment, ok := assAlignment[style.Position]
+	if !ok {
+		alignment = assAlignment[PositionBottom]
+	}
+	return fmt.Sprintf("Style: %s,%s,54,%s,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,2,1,%d,80,80,50,1\n",
+		name, strings.ReplaceAll(font, ",", " "), assColor(style.Color, "&H00FFFFFF"), alignment)
+}
+
+// assStyleName returns the name of a speaker's style
+func assStyleName(speaker string) string {
+	return "Speaker " + strings.ReplaceAll(speaker, ",", " ")
+}
+
+// renderASS formats the transcription as Advanced SubStation Alpha subtitles with
+// a style per speaker from opts.Styles. ffmpeg's ass filter burns these into video.
+func renderASS(transcription *Result, opts RenderOptions) string {
+	var output strings.Builder
+	output.WriteString(assHeader)
+	output.WriteString(assStyle("Default", opts.Styles.For("")))
+	for _, speaker := range speakerOrder(transcription) {
+		output.WriteString(assStyle(assStyleName(speaker), opts.Styles.For(speaker)))
+	}
+
+	output.WriteString("\n[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+	for _, c := range subtitleCues(transcription, opts.SpeakerPrefix, opts.optionInt("ass", "line-width"), opts.optionInt("ass", "max-lines")) {
+		lines := make([]string, len(c.Lines))
+		for i, line := range c.Lines {
+			lines[i] = assEscaper.Replace(line)
+		}
+		output.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,%s,%s,0,0,0,,%s\n",
+			assTimestamp(c.Start), assTimestamp(c.End), assStyleName(c.Speaker), strings.ReplaceAll(c.Speaker, ",", " "), strings.Join(lines, `\N`)))
+	}
+	return output.String()
+}
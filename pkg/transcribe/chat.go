@@ -0,0 +1,77 @@
+package transcribe
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// chatSchemaVersion is bumped whenever a field of chatDocument is renamed,
+// removed or changes meaning
+const chatSchemaVersion = 1
+
+// chatDocument is the --format chat-json output: the conversation as turns that
+// alternate between speakers, for conversation analysis and dialogue datasets
+type chatDocument struct {
+	SchemaVersion int        `json:"schema_version"`
+	TranscriptID  string     `json:"transcript_id"`
+	LanguageCode  string     `json:"language_code,omitempty"`
+	Speakers      []string   `json:"speakers"`
+	Turns         []chatTurn `json:"turns"`
+}
+
+// chatTurn is the consecutive segments of one speaker; times are in milliseconds
+type chatTurn struct {
+	Speaker  string `json:"speaker"`
+	StartMS  int    `json:"start_ms"`
+	EndMS    int    `json:"end_ms"`
+	Text     string `json:"text"`
+	Segments int    `json:"segments"`
+	// GapMS is the silence between the end of the previous turn and this one;
+	// OverlapMS is how long this turn starts before the previous one ends
+	GapMS     int `json:"gap_ms,omitempty"`
+	OverlapMS int `json:"overlap_ms,omitempty"`
+}
+
+// renderChatJSON formats the transcription as alternating speaker turns, merging
+// the consecutive segments of a speaker and annotating the gap or overlap at
+// every change of speaker
+func renderChatJSON(transcription *Result, opts RenderOptions) string {
+	doc := chatDocument{
+		SchemaVersion: chatSchemaVersion,
+		TranscriptID:  transcription.ID,
+		LanguageCode:  transcription.LanguageCode,
+		Speakers:      []string{},
+		Turns:         []chatTurn{},
+	}
+	for _, utterance := range transcription.Utterances {
+		text := strings.TrimSpace(utterance.Text)
+		if text == "" {
+			continue
+		}
+		if !slices.Contains(doc.Speakers, utterance.Speaker) {
+			doc.Speakers = append(doc.Speakers, utterance.Speaker)
+		}
+
+		if n := len(doc.Turns); n > 0 && doc.Turns[n-1].Speaker == utterance.Speaker {
+			turn := &doc.Turns[n-1]
+			turn.EndMS = max(turn.EndMS, utterance.End)
+			turn.Text = JoinSentences(turn.Text, text)
+			turn.Segments++
+			continue
+		}
+
+		turn := chatTurn{Speaker: utterance.Speaker, StartMS: utterance.Start, EndMS: utterance.End, Text: text, Segments: 1}
+		if n := len(doc.Turns); n > 0 {
+			if gap := utterance.Start - doc.Turns[n-1].EndMS; gap > 0 {
+				turn.GapMS = gap
+			} else {
+				turn.OverlapMS = -gap
+			}
+		}
+		doc.Turns = append(doc.Turns, turn)
+	}
+
+	data, _ := json.MarshalIndent(doc, "", "  ")
+	return string(data) + "\n"
+}
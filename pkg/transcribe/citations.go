@@ -0,0 +1,74 @@
+package transcribe
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	bibtexSpecial = regexp.MustCompile(`([&%$#_{}])`)
+	keyPattern    = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// citationSpeaker returns how a speaker is credited in a citation
+func citationSpeaker(speaker string) string {
+	if speaker == "" {
+		return "Unknown speaker"
+	}
+	return "Speaker " + speaker
+}
+
+// renderCitations formats each segment as a quotable citation with its speaker,
+// time code and source title, either in APA style or as BibTeX entries
+func renderCitations(transcription *Result, opts RenderOptions) string {
+	utterances := transcription.Utterances
+	if len(utterances) == 0 && transcription.Text != "" {
+		utterances = []Segment{{Text: transcription.Text}}
+	}
+
+	var output strings.Builder
+	for i, utterance := range utterances {
+		timeCode := fmt.Sprintf("%s–%s", FormatTimestamp(float64(utterance.Start)/1000.0), FormatTimestamp(float64(utterance.End)/1000.0))
+		if opts.CitationStyle == "bibtex" {
+			output.WriteString(bibtexCitation(i+1, utterance, timeCode, opts))
+		} else {
+			output.WriteString(apaCitation(utterance, timeCode, opts))
+		}
+		output.WriteString("\n")
+	}
+	return output.String()
+}
+
+// apaCitation formats a segment in APA style for an audio recording
+func apaCitation(utterance Segment, timeCode string, opts RenderOptions) string {
+	date := "n.d."
+	if !opts.Recorded.IsZero() {
+		date = opts.Recorded.Format("2006, January 2")
+	}
+	return fmt.Sprintf("%s. (%s). %s [Audio recording], %s.\n    \"%s\"\n",
+		citationSpeaker(utterance.Speaker), date, opts.Title, timeCode, strings.TrimSpace(utterance.Text))
+}
+
+// bibtexCitation formats a segment as a BibTeX @misc entry
+func bibtexCitation(n int, utterance Segment, timeCode string, opts RenderOptions) string {
+	escape := func(s string) string { return bibtexSpecial.ReplaceAllString(s, `\$1`) }
+
+	key := keyPattern.ReplaceAllString(strings.ToLower(opts.Title), "")
+	if key == "" {
+		key = "transcript"
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("@misc{%s%d,\n", key, n))
+	output.WriteString(fmt.Sprintf("  author = {%s},\n", escape(citationSpeaker(utterance.Speaker))))
+	output.WriteString(fmt.Sprintf("  title = {%s},\n", escape(opts.Title)))
+	if !opts.Recorded.IsZero() {
+		output.WriteString(fmt.Sprintf("  year = {%d},\n", opts.Recorded.Year()))
+		output.WriteString(fmt.Sprintf("  month = %s,\n", strings.ToLower(opts.Recorded.Month().String()[:3])))
+	}
+	output.WriteString(fmt.Sprintf("  howpublished = {Audio recording, %s},\n", strings.ReplaceAll(timeCode, "–", "--")))
+	output.WriteString(fmt.Sprintf("  note = {``%s''},\n", escape(strings.TrimSpace(utterance.Text))))
+	output.WriteString("}\n")
+	return output.String()
+}
@@ -0,0 +1,28 @@
+package transcribe
+
+import (
+	"encoding/csv"
+	"strings"
+)
+
+// renderCSV formats the segments as CSV with speaker, start, end and text columns.
+// Times are HH:MM:SS.mmm, which spreadsheets read as durations. The delimiter is
+// the csv.delimiter option.
+func renderCSV(transcription *Result, opts RenderOptions) string {
+	var output strings.Builder
+	w := csv.NewWriter(&output)
+	if delimiter := []rune(opts.option("csv", "delimiter")); len(delimiter) == 1 {
+		w.Comma = delimiter[0]
+	}
+	w.Write([]string{"speaker", "start", "end", "text"})
+	for _, utterance := range transcription.Utterances {
+		w.Write([]string{
+			utterance.Speaker,
+			formatSubtitleTimestamp(utterance.Start, "."),
+			formatSubtitleTimestamp(utterance.End, "."),
+			strings.TrimSpace(utterance.Text),
+		})
+	}
+	w.Flush()
+	return output.String()
+}
@@ -0,0 +1,75 @@
+package transcribe
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// fcpxmlFrameDuration returns the frame duration of fps as the numerator and
+// denominator of a rational number of seconds, e.g. 1001/30000 for 29.97
+func fcpxmlFrameDuration(fps float64) (int, int) {
+	rate, multiplier := ttmlFrameRate(fps)
+	if multiplier != "" {
+		return 1001, rate * 1000
+	}
+	return 100, rate * 100
+}
+
+// fcpxmlTime formats ms as a rational time in whole frames, as FCPXML requires
+func fcpxmlTime(ms int, num, den int) string {
+	frames := int(math.Round(float64(ms) / 1000.0 * float64(den) / float64(num)))
+	if frames == 0 {
+		return "0s"
+	}
+	return fmt.Sprintf("%d/%ds", frames*num, den)
+}
+
+// XMLEscape escapes text for use in XML content and attributes
+func XMLEscape(text string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(text))
+	return escaped.String()
+}
+
+// renderFCPXML formats the transcription as an FCPXML project with a caption roll
+// on a gap, which Final Cut Pro imports as a timeline of ITT captions
+func renderFCPXML(transcription *Result, opts RenderOptions) string {
+	num, den := fcpxmlFrameDuration(opts.FrameRate)
+	lang := languageTag(transcription.LanguageCode)
+	cues := subtitleCues(transcription, opts.SpeakerPrefix, opts.optionInt("fcpxml", "line-width"), opts.optionInt("fcpxml", "max-lines"))
+	end := 0
+	if len(cues) > 0 {
+		end = cues[len(cues)-1].End
+	}
+	duration := fcpxmlTime(end, num, den)
+
+	var output strings.Builder
+	output.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE fcpxml>\n<fcpxml version=\"1.9\">\n")
+	output.WriteString("  <resources>\n")
+	output.WriteString(fmt.Sprintf("    <format id=\"r1\" frameDuration=\"%d/%ds\" width=\"1920\" height=\"1080\"/>\n", num, den))
+	output.WriteString("  </resources>\n  <library>\n    <event name=\"Transcript\">\n")
+	output.WriteString(fmt.Sprintf("      <project name=\"%s\">\n", XMLEscape(opts.Title)))
+	output.WriteString(fmt.Sprintf("        <sequence format=\"r1\" duration=\"%s\" tcStart=\"0s\" tcFormat=\"NDF\">\n          <spine>\n", duration))
+	output.WriteString(fmt.Sprintf("            <gap name=\"Gap\" offset=\"0s\" duration=\"%s\" start=\"0s\">\n", duration))
+	for i, c := range cues {
+		text := XMLEscape(strings.Join(c.Lines, "\n"))
+		output.WriteString(fmt.Sprintf("              <caption lane=\"1\" offset=\"%s\" duration=\"%s\" role=\"iTT?captionFormat=ITT.%s\" name=\"%s\">\n",
+			fcpxmlTime(c.Start, num, den), fcpxmlTime(c.End-c.Start, num, den), lang, XMLEscape(Excerpt(strings.Join(c.Lines, " "), 6))))
+		output.WriteString(fmt.Sprintf("                <text placement=\"bottom\"><text-style ref=\"ts%d\">%s</text-style></text>\n", i+1, text))
+		output.WriteString(fmt.Sprintf("                <text-style-def id=\"ts%d\"><text-style font=\".SF NS Text\" fontSize=\"13\" fontFace=\"Regular\" fontColor=\"1 1 1 1\" backgroundColor=\"0 0 0 1\"/></text-style-def>\n", i+1))
+		output.WriteString("              </caption>\n")
+	}
+	output.WriteString("            </gap>\n          </spine>\n        </sequence>\n      </project>\n    </event>\n  </library>\n</fcpxml>\n")
+	return output.String()
+}
+
+// Excerpt returns the first n words of text
+func Excerpt(text string, n int) string {
+	words := strings.Fields(text)
+	if len(words) <= n {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(words[:n], " ") + "..."
+}
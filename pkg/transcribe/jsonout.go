@@ -0,0 +1,77 @@
+package transcribe
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// transcriptSchemaVersion is bumped whenever a field of transcriptDocument is
+// renamed, removed or changes meaning; adding fields does not bump it
+const transcriptSchemaVersion = 1
+
+// transcriptDocument is the --format json output. Unlike the API response it is
+// a stable schema owned by this tool, and it reflects the processed transcript
+// (ignore filters, terminology).
+type transcriptDocument struct {
+	SchemaVersion int               `json:"schema_version"`
+	TranscriptID  string            `json:"transcript_id"`
+	LanguageCode  string            `json:"language_code,omitempty"`
+	Header        []string          `json:"header,omitempty"`
+	Speakers      []string          `json:"speakers"`
+	Segments      []documentSegment `json:"segments"`
+	Chapters      []documentChapter `json:"chapters,omitempty"`
+	Text          string            `json:"text"`
+}
+
+// documentSegment is one speaker turn; times are in milliseconds
+type documentSegment struct {
+	Speaker    string  `json:"speaker"`
+	StartMS    int     `json:"start_ms"`
+	EndMS      int     `json:"end_ms"`
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"`
+}
+
+// documentChapter is an automatically detected chapter; times are in milliseconds
+type documentChapter struct {
+	StartMS  int    `json:"start_ms"`
+	EndMS    int    `json:"end_ms"`
+	Headline string `json:"headline"`
+	Summary  string `json:"summary"`
+}
+
+// renderJSON formats the transcription as a versioned transcriptDocument
+func renderJSON(transcription *Result, opts RenderOptions) string {
+	doc := transcriptDocument{
+		SchemaVersion: transcriptSchemaVersion,
+		TranscriptID:  transcription.ID,
+		LanguageCode:  transcription.LanguageCode,
+		Header:        opts.Header,
+		Speakers:      []string{},
+		Segments:      []documentSegment{},
+		Text:          TranscriptText(transcription),
+	}
+	for _, utterance := range transcription.Utterances {
+		if !slices.Contains(doc.Speakers, utterance.Speaker) {
+			doc.Speakers = append(doc.Speakers, utterance.Speaker)
+		}
+		doc.Segments = append(doc.Segments, documentSegment{
+			Speaker:    utterance.Speaker,
+			StartMS:    utterance.Start,
+			EndMS:      utterance.End,
+			Text:       utterance.Text,
+			Confidence: utterance.Confidence,
+		})
+	}
+	for _, chapter := range transcription.Chapters {
+		doc.Chapters = append(doc.Chapters, documentChapter{
+			StartMS:  chapter.Start,
+			EndMS:    chapter.End,
+			Headline: chapter.Headline,
+			Summary:  chapter.Summary,
+		})
+	}
+
+	data, _ := json.MarshalIndent(doc, "", "  ")
+	return string(data) + "\n"
+}
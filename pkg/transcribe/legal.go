@@ -0,0 +1,156 @@
+package transcribe
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// legalLinesPerPage is the number of numbered lines on each transcript page
+	legalLinesPerPage = 25
+	// legalLineWidth is the maximum number of characters of text on a line
+	legalLineWidth = 56
+)
+
+// wrapText breaks text into lines of at most width characters at word boundaries.
+// Words longer than width get a line of their own. Text in scripts written without
+// spaces, such as Chinese and Japanese, may break between any two characters except
+// before closing punctuation.
+func wrapText(text string, width int) []string {
+	var lines []string
+	var line strings.Builder
+	length := 0
+	for _, word := range strings.Fields(text) {
+		for i, piece := range wrapPieces(word) {
+			separator := i == 0 && length > 0
+			pieceLength := len([]rune(piece))
+			if separator {
+				pieceLength++
+			}
+			if length > 0 && length+pieceLength > width {
+				lines = append(lines, line.String())
+				line.Reset()
+				length, separator, pieceLength = 0, false, len([]rune(piece))
+			}
+			if separator {
+				line.WriteString(" ")
+			}
+			line.WriteString(piece)
+			length += pieceLength
+		}
+	}
+	if line.Len() > 0 {
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// wrapPieces splits a whitespace-delimited word into the units a line may break
+// between: the word itself, or for CJK text each character together with the
+// punctuation and Latin letters that follow it
+func wrapPieces(word string) []string {
+	if !strings.ContainsFunc(word, isCJK) {
+		return []string{word}
+	}
+	var pieces []string
+	for _, r := range word {
+		if len(pieces) > 0 && !isCJK(r) {
+			pieces[len(pieces)-1] += string(r)
+			continue
+		}
+		pieces = append(pieces, string(r))
+	}
+	return pieces
+}
+
+// renderLegal formats the transcription in court-reporter style: pages of 25
+// numbered lines with a page header, speaker names in capitals followed by a colon,
+// and a certificate page at the end
+func renderLegal(transcription *Result, opts RenderOptions) string {
+	var lines []string
+	for _, line := range opts.Header {
+		lines = append(lines, wrapText(line, legalLineWidth)...)
+	}
+	if len(opts.Header) > 0 {
+		lines = append(lines, "")
+	}
+
+	agenda := opts.Agenda
+	if len(transcription.Utterances) > 0 {
+		currentSpeaker := ""
+		for _, utterance := range transcription.Utterances {
+			for len(agenda) > 0 && agenda[0].Start <= time.Duration(utterance.Start)*time.Millisecond {
+				lines = append(lines, "", "          "+strings.ToUpper(agenda[0].Heading), "")
+				agenda = agenda[1:]
+				currentSpeaker = ""
+			}
+
+			speaker := utterance.Speaker
+			if speaker == "" {
+				speaker = "Unknown"
+			}
+
+			text := strings.TrimSpace(utterance.Text)
+			if speaker != currentSpeaker {
+				text = strings.ToUpper("Speaker "+speaker) + ": " + text
+				currentSpeaker = speaker
+			}
+			// Each turn starts indented like a new paragraph
+			for i, line := range wrapText(text, legalLineWidth-5) {
+				if i == 0 {
+					line = "     " + line
+				}
+				lines = append(lines, line)
+			}
+		}
+	} else {
+		lines = append(lines, wrapText(transcription.Text, legalLineWidth)...)
+	}
+
+	var output strings.Builder
+	pages := max(1, (len(lines)+legalLinesPerPage-1)/legalLinesPerPage)
+	for page := 0; page < pages; page++ {
+		if page > 0 {
+			output.WriteString("\f")
+		}
+		output.WriteString(fmt.Sprintf("%*s\n\n", legalLineWidth+4, fmt.Sprintf("Page %d", page+1)))
+		for n := 0; n < legalLinesPerPage; n++ {
+			line := ""
+			if i := page*legalLinesPerPage + n; i < len(lines) {
+				line = lines[i]
+			}
+			output.WriteString(strings.TrimRight(fmt.Sprintf("%2d  %s", n+1, line), " ") + "\n")
+		}
+	}
+
+	output.WriteString("\f")
+	output.WriteString(legalCertificate(pages))
+	return output.String()
+}
+
+// legalCertificate is the certificate page template appended to legal transcripts
+func legalCertificate(pages int) string {
+	return fmt.Sprintf(`%*s
+
+                  CERTIFICATE OF TRANSCRIPTION
+
+     I, ______________________________, certify that the
+foregoing pages 1 through %d are a true and accurate
+transcription of the recording named therein, to the best
+of my knowledge and ability.
+
+     I further certify that I am not related to any party
+to this matter and have no interest in its outcome.
+
+
+Date: ____________________
+
+
+_______________________________
+Signature
+
+_______________________________
+Printed name and certification number
+`, legalLineWidth+4, fmt.Sprintf("Page %d", pages+1), pages)
+}
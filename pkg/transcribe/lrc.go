@@ -0,0 +1,32 @@
+package transcribe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lrcTimestamp formats milliseconds as an LRC [mm:ss.xx] tag; minutes are not
+// wrapped into hours
+func lrcTimestamp(ms int) string {
+	return fmt.Sprintf("[%02d:%02d.%02d]", ms/60000, ms/1000%60, ms%1000/10)
+}
+
+// renderLRC formats the transcription as synced lyrics: one timestamped line per
+// segment start, with a blank line at the end of the last segment so players clear it
+func renderLRC(transcription *Result, opts RenderOptions) string {
+	var output strings.Builder
+	if opts.Title != "" {
+		output.WriteString(fmt.Sprintf("[ti:%s]\n", opts.Title))
+	}
+	for _, utterance := range transcription.Utterances {
+		text := strings.Join(strings.Fields(utterance.Text), " ")
+		if opts.SpeakerPrefix && utterance.Speaker != "" {
+			text = fmt.Sprintf("Speaker %s: %s", utterance.Speaker, text)
+		}
+		output.WriteString(lrcTimestamp(utterance.Start) + text + "\n")
+	}
+	if n := len(transcription.Utterances); n > 0 {
+		output.WriteString(lrcTimestamp(transcription.Utterances[n-1].End) + "\n")
+	}
+	return output.String()
+}
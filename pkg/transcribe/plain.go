@@ -0,0 +1,28 @@
+package transcribe
+
+import "strings"
+
+// renderPlain formats the transcription as bare prose for feeding into other
+// tools: one paragraph per speaker turn, without timestamps or speaker labels.
+// Consecutive segments of the same speaker are merged into one paragraph.
+func renderPlain(transcription *Result, opts RenderOptions) string {
+	if len(transcription.Utterances) == 0 {
+		return strings.Join(strings.Fields(transcription.Text), " ") + "\n"
+	}
+
+	var paragraphs []string
+	paragraph := ""
+	speaker := ""
+	for i, utterance := range transcription.Utterances {
+		if i > 0 && utterance.Speaker != speaker && paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+			paragraph = ""
+		}
+		speaker = utterance.Speaker
+		paragraph = JoinSentences(paragraph, strings.Join(strings.Fields(utterance.Text), " "))
+	}
+	if paragraph != "" {
+		paragraphs = append(paragraphs, paragraph)
+	}
+	return strings.Join(paragraphs, "\n\n") + "\n"
+}
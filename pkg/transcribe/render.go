@@ -0,0 +1,284 @@
+package transcribe
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RenderOptions holds extra content woven into rendered transcripts
+type RenderOptions struct {
+	// Agenda headings are inserted before the first utterance at or after their time
+	Agenda []AgendaItem
+	// Header lines are written at the top of the transcript
+	Header []string
+	// Notes are reviewer annotations rendered after the utterance they fall on
+	Notes []Annotation
+	// Title, Recorded and CitationStyle describe the source for the citations
+	// format; Recorded is only set when the date was detected
+	Title         string
+	Recorded      time.Time
+	CitationStyle string
+	// SpeakerPrefix starts subtitles with the speaker label
+	SpeakerPrefix bool
+	// FrameRate switches TTML timestamps to frames at this rate; zero uses clock time
+	FrameRate float64
+	// FormatOptions holds the --<format>.<name> settings by "<format>.<name>"
+	FormatOptions map[string]string
+	// Styles sets the color, font and position of each speaker in the subtitle
+	// formats; nil keeps their defaults
+	Styles *Styles
+}
+
+// AgendaItem is a heading from an agenda file with the rough time it starts at
+type AgendaItem struct {
+	Start   time.Duration
+	Heading string
+}
+
+// Annotation is a reviewer bookmark or comment from a .notes.yaml sidecar file. It
+// refers either to a segment by its 1-based number in the transcript returned by the
+// API, or to a timestamp.
+type Annotation struct {
+	Segment  int    `yaml:"segment"`
+	At       string `yaml:"at"`
+	Author   string `yaml:"author"`
+	Note     string `yaml:"note"`
+	Bookmark bool   `yaml:"bookmark"`
+
+	// Position is where the annotation falls in milliseconds, resolved from
+	// Segment or At when it is loaded
+	Position int `yaml:"-"`
+}
+
+// String formats the annotation as a margin note
+func (a Annotation) String() string {
+	var label string
+	if a.Bookmark {
+		label = "Bookmark"
+	} else {
+		label = "Note"
+	}
+	if a.Author != "" {
+		label += " (" + a.Author + ")"
+	}
+	if a.Note == "" {
+		return label
+	}
+	return label + ": " + a.Note
+}
+
+// notesFor returns the annotations that fall on an utterance, i.e. between its start
+// and the start of the next one (next is -1 for the last utterance)
+func notesFor(annotations []Annotation, start, next int) []Annotation {
+	var notes []Annotation
+	for _, a := range annotations {
+		if a.Position >= start && (next < 0 || a.Position < next) {
+			notes = append(notes, a)
+		}
+	}
+	return notes
+}
+
+// FormatOption is a setting of one output format, set on the command line as
+// --<format>.<name>
+type FormatOption struct {
+	Name    string
+	Default string
+	Usage   string
+	// Int options must parse as a positive integer
+	Int bool
+}
+
+// Format describes a --format value
+type Format struct {
+	// Extension is appended to the output base name
+	Extension string
+	// Render produces the file contents; nil for formats that write more than one
+	// file and are handled by the caller
+	Render  func(*Result, RenderOptions) string
+	Options []FormatOption
+}
+
+// subtitleOptions are the knobs shared by the caption formats
+var subtitleOptions = []FormatOption{
+	{Name: "max-lines", Default: "2", Usage: "maximum number of lines per cue", Int: true},
+	{Name: "line-width", Default: "42", Usage: "maximum number of characters per line", Int: true},
+}
+
+// Formats is the registry of output formats. It is filled in init because
+// the renderers look up their options in it.
+var Formats map[string]Format
+
+func init() {
+	Formats = map[string]Format{
+		"txt":       {Extension: ".txt", Render: renderText},
+		"plain":     {Extension: ".txt", Render: renderPlain},
+		"legal":     {Extension: ".txt", Render: renderLegal},
+		"citations": {Extension: ".citations.txt", Render: renderCitations},
+		"srt":       {Extension: ".srt", Render: renderSRT, Options: subtitleOptions},
+		"vtt":       {Extension: ".vtt", Render: renderVTT, Options: subtitleOptions},
+		"ttml":      {Extension: ".ttml", Render: renderTTML, Options: subtitleOptions},
+		"ass":       {Extension: ".ass", Render: renderASS, Options: subtitleOptions},
+		"fcpxml":    {Extension: ".fcpxml", Render: renderFCPXML, Options: subtitleOptions},
+		"lrc":       {Extension: ".lrc", Render: renderLRC},
+		"rttm":      {Extension: ".rttm", Render: renderRTTM},
+		"json":      {Extension: ".transcript.json", Render: renderJSON},
+		"chat-json": {Extension: ".chat.json", Render: renderChatJSON},
+		"csv": {Extension: ".csv", Render: renderCSV, Options: []FormatOption{
+			{Name: "delimiter", Default: ",", Usage: "field delimiter, e.g. ; for spreadsheets in locales with decimal commas"},
+		}},
+		// dataset writes a directory of clips with a metadata.csv
+		"dataset": {Extension: "-dataset"},
+	}
+}
+
+// FormatNames returns the registered format names in order
+func FormatNames() []string {
+	names := make([]string, 0, len(Formats))
+	for name := range Formats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// option returns the value of a format option, falling back to its default
+func (o RenderOptions) option(format, name string) string {
+	if value, ok := o.FormatOptions[format+"."+name]; ok {
+		return value
+	}
+	for _, option := range Formats[format].Options {
+		if option.Name == name {
+			return option.Default
+		}
+	}
+	return ""
+}
+
+// optionInt returns the value of an integer format option
+func (o RenderOptions) optionInt(format, name string) int {
+	n, _ := strconv.Atoi(o.option(format, name))
+	return n
+}
+
+// Render formats the transcription in the given output format
+func Render(format string, transcription *Result, opts RenderOptions) string {
+	render := renderText
+	if f, ok := Formats[format]; ok && f.Render != nil {
+		render = f.Render
+	}
+	return render(transcription, opts)
+}
+
+// renderText formats the transcription with speaker labels and timestamps
+func renderText(transcription *Result, opts RenderOptions) string {
+	var output strings.Builder
+	for _, line := range opts.Header {
+		output.WriteString(line + "\n")
+	}
+	if len(opts.Header) > 0 {
+		output.WriteString("\n")
+	}
+
+	agenda := opts.Agenda
+
+	// If we have utterances with speaker info, format them nicely
+	if len(transcription.Utterances) > 0 {
+		currentSpeaker := ""
+		for i, utterance := range transcription.Utterances {
+			for len(agenda) > 0 && agenda[0].Start <= time.Duration(utterance.Start)*time.Millisecond {
+				if output.Len() > 0 {
+					output.WriteString("\n")
+				}
+				output.WriteString(fmt.Sprintf("=== %s (%s) ===\n\n", agenda[0].Heading, FormatTimestamp(agenda[0].Start.Seconds())))
+				agenda = agenda[1:]
+				// Repeat the speaker header at the start of each section
+				currentSpeaker = ""
+			}
+
+			// Format timestamps (convert milliseconds to HH:MM:SS)
+			startTime := FormatTimestamp(float64(utterance.Start) / 1000.0)
+			endTime := FormatTimestamp(float64(utterance.End) / 1000.0)
+
+			speaker := utterance.Speaker
+			if speaker == "" {
+				speaker = "Unknown"
+			}
+
+			// Add speaker header if speaker changes
+			if speaker != currentSpeaker {
+				if currentSpeaker != "" {
+					output.WriteString("\n")
+				}
+				output.WriteString(fmt.Sprintf("[%s - %s] Speaker %s:\n", startTime, endTime, speaker))
+				currentSpeaker = speaker
+			} else {
+				output.WriteString(fmt.Sprintf("[%s - %s] ", startTime, endTime))
+			}
+
+			output.WriteString(strings.TrimSpace(utterance.Text))
+			output.WriteString("\n")
+
+			next := -1
+			if i+1 < len(transcription.Utterances) {
+				next = transcription.Utterances[i+1].Start
+			}
+			for _, note := range notesFor(opts.Notes, utterance.Start, next) {
+				output.WriteString(fmt.Sprintf("    >> %s\n", note))
+			}
+		}
+	} else {
+		// Fallback to plain text if no utterances
+		output.WriteString(transcription.Text)
+		output.WriteString("\n")
+	}
+
+	return output.String()
+}
+
+// FormatTimestamp converts seconds to HH:MM:SS format
+func FormatTimestamp(seconds float64) string {
+	duration := time.Duration(seconds * float64(time.Second))
+	hours := int(duration.Hours())
+	minutes := int(duration.Minutes()) % 60
+	secs := int(duration.Seconds()) % 60
+	return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, secs)
+}
+
+// TranscriptText returns all transcribed text of the transcription
+func TranscriptText(transcription *Result) string {
+	if len(transcription.Utterances) == 0 {
+		return transcription.Text
+	}
+	// As JoinSentences, without copying the text for every segment
+	var text strings.Builder
+	previous := ""
+	for _, utterance := range transcription.Utterances {
+		sentence := strings.TrimSpace(utterance.Text)
+		if sentence == "" {
+			continue
+		}
+		if previous != "" && spacedAfter(previous) {
+			text.WriteByte(' ')
+		}
+		text.WriteString(sentence)
+		previous = sentence
+	}
+	return text.String()
+}
+
+// languageTag converts a language code of the API such as "en_us" to the BCP 47
+// tag "en-US" that caption formats expect, defaulting to English
+func languageTag(code string) string {
+	if code == "" {
+		return "en"
+	}
+	language, region, ok := strings.Cut(code, "_")
+	if !ok {
+		return language
+	}
+	return language + "-" + strings.ToUpper(region)
+}
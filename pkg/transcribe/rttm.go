@@ -0,0 +1,38 @@
+package transcribe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// rttmFileID returns the RTTM file identifier for title; fields are separated by
+// spaces, so the identifier may not contain any
+func rttmFileID(title string) string {
+	if id := strings.Join(strings.Fields(title), "_"); id != "" {
+		return id
+	}
+	return "audio"
+}
+
+// renderRTTM formats the speaker turns as NIST RTTM for scoring with dscore or
+// pyannote.metrics:
+//
+//	SPEAKER <file> 1 <onset> <duration> <NA> <NA> <speaker> <NA> <NA>
+//
+// with onset and duration in seconds
+func renderRTTM(transcription *Result, opts RenderOptions) string {
+	fileID := rttmFileID(opts.Title)
+	var output strings.Builder
+	for _, utterance := range transcription.Utterances {
+		if utterance.End <= utterance.Start {
+			continue
+		}
+		speaker := strings.Join(strings.Fields(utterance.Speaker), "_")
+		if speaker == "" {
+			speaker = "unknown"
+		}
+		output.WriteString(fmt.Sprintf("SPEAKER %s 1 %.3f %.3f <NA> <NA> %s <NA> <NA>\n",
+			fileID, float64(utterance.Start)/1000.0, float64(utterance.End-utterance.Start)/1000.0, speaker))
+	}
+	return output.String()
+}
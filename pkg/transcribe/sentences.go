@@ -0,0 +1,150 @@
+package transcribe
+
+import (
+	"strings"
+	"unicode"
+)
+
+// abbreviations lists, per language, words ending in a period that do not end a
+// sentence. Entries are lowercase and include the final period.
+var abbreviations = map[string][]string{
+	"en": {"mr.", "mrs.", "ms.", "dr.", "prof.", "sr.", "jr.", "st.", "vs.", "etc.", "e.g.", "i.e.", "approx.", "inc.", "ltd.", "co.", "no.", "jan.", "feb.", "mar.", "apr.", "jun.", "jul.", "aug.", "sep.", "sept.", "oct.", "nov.", "dec."},
+	"tr": {"dr.", "prof.", "doç.", "av.", "sn.", "vb.", "vs.", "bkz.", "yy.", "örn.", "no.", "tel.", "ş.", "a.ş."},
+	"de": {"dr.", "prof.", "hr.", "fr.", "z.b.", "usw.", "bzw.", "ca.", "evtl.", "ggf.", "nr.", "str.", "d.h."},
+	"fr": {"m.", "mme.", "mlle.", "dr.", "pr.", "etc.", "cf.", "p.ex.", "n°."},
+	"es": {"sr.", "sra.", "srta.", "dr.", "dra.", "ud.", "uds.", "etc.", "p.ej."},
+}
+
+// isCJK reports whether r belongs to a script written without spaces between words
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) && !unicode.IsSpace(r)
+}
+
+// isFullWidthTerminator reports whether r ends a sentence in CJK text, where no
+// space follows the terminator
+func isFullWidthTerminator(r rune) bool {
+	return r == '。' || r == '！' || r == '？' || r == '．'
+}
+
+// isAbbreviation reports whether word, which ends in a period, is a known
+// abbreviation in lang
+func isAbbreviation(word, lang string) bool {
+	lower := strings.ToLower(strings.TrimLeft(word, "(\"'“‘"))
+	base, _, _ := strings.Cut(lang, "_")
+	for _, abbreviation := range abbreviations[base] {
+		if lower == abbreviation {
+			return true
+		}
+	}
+	return false
+}
+
+// isInitial reports whether word, which ends in a period, is the initial of a name
+// as in "J. Smith" or "John F. Kennedy": a capital letter followed by a
+// capitalized word, and either starting the sentence or following a capitalized
+// word or another initial. A one-letter word such as "plan B." ends a sentence.
+func isInitial(previous, word, next string) bool {
+	letters := []rune(strings.TrimLeft(word, "(\"'“‘"))
+	if len(letters) != 2 || !unicode.IsUpper(letters[0]) || !unicode.IsUpper(firstLetter(next)) {
+		return false
+	}
+	return previous == "" || unicode.IsUpper(firstLetter(previous))
+}
+
+// firstLetter returns the first letter of word, skipping opening quotes and
+// brackets, or zero if there is none
+func firstLetter(word string) rune {
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		if !strings.ContainsRune("(\"'“‘", r) {
+			return 0
+		}
+	}
+	return 0
+}
+
+// SplitSentences splits text into sentences for language lang (an ISO 639-1 code
+// such as "en" or "tr", possibly with a region). It handles abbreviations and
+// initials, decimal numbers, ordinals written as "3." followed by a lowercase word
+// (common in Turkish and German), ellipses, closing quotes after the terminator
+// and CJK full-width terminators.
+func SplitSentences(text, lang string) []string {
+	var sentences []string
+	runes := []rune(strings.TrimSpace(text))
+	start := 0
+	flush := func(end int) {
+		if sentence := strings.TrimSpace(string(runes[start:end])); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start = end
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if isFullWidthTerminator(r) {
+			for i+1 < len(runes) && (isFullWidthTerminator(runes[i+1]) || strings.ContainsRune("」』）\"”", runes[i+1])) {
+				i++
+			}
+			flush(i + 1)
+			continue
+		}
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+
+		// Take in repeated terminators, ellipses and closing quotes or brackets
+		end := i + 1
+		for end < len(runes) && strings.ContainsRune(".!?…\"'”’)]", runes[end]) {
+			end++
+		}
+		if end < len(runes) && !unicode.IsSpace(runes[end]) {
+			// 3.14, e.g., example.com
+			i = end - 1
+			continue
+		}
+
+		next := end
+		for next < len(runes) && unicode.IsSpace(runes[next]) {
+			next++
+		}
+		if r == '.' && end == i+1 {
+			wordStart := i
+			for wordStart > start && !unicode.IsSpace(runes[wordStart-1]) {
+				wordStart--
+			}
+			word := string(runes[wordStart : i+1])
+			previous, following := "", ""
+			if fields := strings.Fields(string(runes[start:wordStart])); len(fields) > 0 {
+				previous = fields[len(fields)-1]
+			}
+			if fields := strings.Fields(string(runes[next:min(next+64, len(runes))])); len(fields) > 0 {
+				following = fields[0]
+			}
+			if isAbbreviation(word, lang) || isInitial(previous, word, following) {
+				i = end - 1
+				continue
+			}
+			// Ordinals: "3. sınıf", "am 3. Mai" (but a capital may also start a sentence)
+			if unicode.IsDigit(runes[i-min(i, 1)]) && next < len(runes) && unicode.IsLower(runes[next]) {
+				i = end - 1
+				continue
+			}
+		}
+		flush(end)
+		i = end - 1
+	}
+	flush(len(runes))
+	return sentences
+}
+
+// EndsSentence reports whether text ends with a sentence terminator
+func EndsSentence(text string) bool {
+	text = strings.TrimRight(strings.TrimSpace(text), "\"'”’)]」』）")
+	if text == "" {
+		return false
+	}
+	last := []rune(text)[len([]rune(text))-1]
+	return last == '.' || last == '!' || last == '?' || last == '…' || isFullWidthTerminator(last)
+}
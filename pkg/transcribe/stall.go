@@ -0,0 +1,128 @@
+package transcribe
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrStalled is returned for a request that made no progress for the
+// StallTimeout of the client
+var ErrStalled = errors.New("stalled")
+
+// maxStalledPolls is how many stalled polls in a row Wait retries
+const maxStalledPolls = 3
+
+// watchdog cancels a request when its body or response has not moved for the
+// timeout
+type watchdog struct {
+	timeout time.Duration
+	cancel  context.CancelCauseFunc
+	last    atomic.Int64
+	done    chan struct{}
+	once    sync.Once
+}
+
+// startWatchdog returns ctx that is canceled with ErrStalled when the returned
+// watchdog is not touched for timeout
+func startWatchdog(ctx context.Context, timeout time.Duration) (context.Context, *watchdog) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	w := &watchdog{timeout: timeout, cancel: cancel, done: make(chan struct{})}
+	w.touch()
+	go func() {
+		ticker := time.NewTicker(max(min(timeout/4, time.Second), time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-ticker.C:
+				if time.Since(time.Unix(0, w.last.Load())) >= timeout {
+					cancel(ErrStalled)
+					return
+				}
+			}
+		}
+	}()
+	return ctx, w
+}
+
+// touch records progress
+func (w *watchdog) touch() {
+	w.last.Store(time.Now().UnixNano())
+}
+
+// stop ends the watch and releases the context. It is safe on a nil watchdog.
+func (w *watchdog) stop() {
+	if w == nil {
+		return
+	}
+	w.once.Do(func() {
+		close(w.done)
+		w.cancel(context.Canceled)
+	})
+}
+
+// stalled returns ErrStalled with the timeout if ctx was canceled by the watchdog,
+// and err otherwise
+func (w *watchdog) stalled(ctx context.Context, err error) error {
+	if w != nil && errors.Is(context.Cause(ctx), ErrStalled) {
+		return &stallError{timeout: w.timeout}
+	}
+	return err
+}
+
+// stallError is ErrStalled with the time without progress
+type stallError struct {
+	timeout time.Duration
+}
+
+func (e *stallError) Error() string {
+	return ErrStalled.Error() + " with no progress for " + e.timeout.String()
+}
+
+func (e *stallError) Unwrap() error {
+	return ErrStalled
+}
+
+// requestBody touches its watchdog as the request body is sent
+type requestBody struct {
+	io.ReadCloser
+	dog *watchdog
+}
+
+func (b *requestBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.dog.touch()
+	}
+	return n, err
+}
+
+// responseBody touches its watchdog as the response is read and stops it when
+// closed
+type responseBody struct {
+	io.ReadCloser
+	ctx context.Context
+	dog *watchdog
+}
+
+func (b *responseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.dog.touch()
+	}
+	if err != nil && err != io.EOF {
+		err = b.dog.stalled(b.ctx, err)
+	}
+	return n, err
+}
+
+func (b *responseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.dog.stop()
+	return err
+}
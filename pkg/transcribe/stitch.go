@@ -0,0 +1,73 @@
+package transcribe
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Patch replaces the segments of result that start between start and end by
+// replacement, e.g. a re-transcription of that range. An end of zero means the
+// end of the recording. Replacement times outside the range are repaired with
+// ValidateTimes, and the repairs are returned.
+func Patch(result *Result, start, end time.Duration, replacement []Segment) []string {
+	// Timestamps are expected relative to the whole file; shift them if the API
+	// returned them relative to the start of the range
+	offset := int(start.Milliseconds())
+	if len(replacement) > 0 && replacement[0].Start < offset {
+		for i := range replacement {
+			replacement[i].Start += offset
+			replacement[i].End += offset
+		}
+	}
+
+	repairs := ValidateTimes(replacement, offset, int(end.Milliseconds()))
+
+	segments := slices.DeleteFunc(result.Utterances, func(segment Segment) bool {
+		position := time.Duration(segment.Start) * time.Millisecond
+		return position >= start && (end == 0 || position < end)
+	})
+	at, _ := slices.BinarySearchFunc(segments, offset, func(segment Segment, offset int) int {
+		return segment.Start - offset
+	})
+	result.Utterances = slices.Insert(segments, at, replacement...)
+	result.Text = TranscriptText(result)
+	return repairs
+}
+
+// ValidateTimes repairs segment times that the API occasionally returns out of
+// range, before they are merged into a timeline. Times are clamped to start and
+// end (milliseconds; an end of zero means unbounded), a segment that starts
+// before the one before it is moved up to that segment's start, and a segment
+// that ends before it starts is given zero length. It returns a description of
+// each repair.
+func ValidateTimes(segments []Segment, start, end int) []string {
+	var repairs []string
+	repair := func(i int, format string, args ...any) {
+		s := segments[i]
+		repairs = append(repairs, fmt.Sprintf("segment %d (%s-%s): %s", i+1,
+			FormatTimestamp(float64(s.Start)/1000.0), FormatTimestamp(float64(s.End)/1000.0), fmt.Sprintf(format, args...)))
+	}
+
+	for i := range segments {
+		s := &segments[i]
+		if s.Start < start {
+			repair(i, "starts before %s, clamped", FormatTimestamp(float64(start)/1000.0))
+			s.Start = start
+		}
+		if end > 0 && s.End > end {
+			repair(i, "ends after the audio at %s, clamped", FormatTimestamp(float64(end)/1000.0))
+			s.End = end
+			s.Start = min(s.Start, end)
+		}
+		if i > 0 && s.Start < segments[i-1].Start {
+			repair(i, "starts before the previous segment, moved to its start")
+			s.Start = segments[i-1].Start
+		}
+		if s.End < s.Start {
+			repair(i, "ends before it starts, given zero length")
+			s.End = s.Start
+		}
+	}
+	return repairs
+}
@@ -0,0 +1,66 @@
+package transcribe
+
+import "strings"
+
+// Caption positions of a Style
+const (
+	PositionBottom = "bottom"
+	PositionMiddle = "middle"
+	PositionTop    = "top"
+)
+
+// Style is how the captions and transcript lines of a speaker look. Empty fields
+// leave the choice to the output format.
+type Style struct {
+	// Color is a #rrggbb color
+	Color string `yaml:"color" json:"color,omitempty"`
+	Font  string `yaml:"font" json:"font,omitempty"`
+	// Position places captions at the bottom (the default), middle or top of the
+	// picture
+	Position string `yaml:"position" json:"position,omitempty"`
+}
+
+// Styles is a brand styling shared by the subtitle and HTML outputs
+type Styles struct {
+	Default Style `yaml:"default" json:"default"`
+	// Speakers holds the styles of speakers by label, e.g. "A"
+	Speakers map[string]Style `yaml:"speakers" json:"speakers,omitempty"`
+}
+
+// For returns the style of a speaker: its own settings over the default. A label
+// with a role such as "A (host)" also matches the entry for "A". For a nil
+// Styles it returns the zero Style.
+func (s *Styles) For(speaker string) Style {
+	if s == nil {
+		return Style{}
+	}
+	style := s.Default
+	own, ok := s.Speakers[speaker]
+	if !ok {
+		label, _, _ := strings.Cut(speaker, " (")
+		own = s.Speakers[label]
+	}
+	if own.Color != "" {
+		style.Color = own.Color
+	}
+	if own.Font != "" {
+		style.Font = own.Font
+	}
+	if own.Position != "" {
+		style.Position = own.Position
+	}
+	return style
+}
+
+// speakerOrder returns the speakers of the utterances in order of appearance
+func speakerOrder(transcription *Result) []string {
+	var speakers []string
+	seen := make(map[string]bool)
+	for _, utterance := range transcription.Utterances {
+		if !seen[utterance.Speaker] {
+			seen[utterance.Speaker] = true
+			speakers = append(speakers, utterance.Speaker)
+		}
+	}
+	return speakers
+}
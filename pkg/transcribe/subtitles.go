@@ -0,0 +1,183 @@
+package transcribe
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// cue is a single subtitle with its display interval in milliseconds
+type cue struct {
+	Start   int
+	End     int
+	Speaker string
+	Lines   []string
+}
+
+// subtitleCues splits the utterances into cues of at most maxLines lines of
+// lineWidth characters, as set by the <format>.max-lines and <format>.line-width
+// options. Cues are filled with whole sentences where they fit so that a cue does
+// not end in the middle of one; a sentence longer than a cue is split at line
+// boundaries.
+// The API has no word timings in the utterances we keep, so an utterance that
+// needs several cues has its duration shared out by the length of their text.
+func subtitleCues(transcription *Result, speakerPrefix bool, lineWidth, maxLines int) []cue {
+	var cues []cue
+	for _, utterance := range transcription.Utterances {
+		sentences := SplitSentences(utterance.Text, transcription.LanguageCode)
+		if len(sentences) == 0 {
+			continue
+		}
+		if speakerPrefix && utterance.Speaker != "" {
+			sentences[0] = fmt.Sprintf("Speaker %s: %s", utterance.Speaker, sentences[0])
+		}
+
+		var groups [][]string
+		var text string
+		for _, sentence := range sentences {
+			if text != "" {
+				joined := JoinSentences(text, sentence)
+				if len(wrapText(joined, lineWidth)) <= maxLines {
+					text = joined
+					continue
+				}
+				groups = append(groups, wrapText(text, lineWidth))
+			}
+			lines := wrapText(sentence, lineWidth)
+			for len(lines) > maxLines {
+				groups = append(groups, lines[:maxLines])
+				lines = lines[maxLines:]
+			}
+			text = lines[0]
+			for _, line := range lines[1:] {
+				text = JoinSentences(text, line)
+			}
+		}
+		groups = append(groups, wrapText(text, lineWidth))
+
+		total := 0
+		for _, group := range groups {
+			total += textLength(group)
+		}
+
+		start, done := utterance.Start, 0
+		for _, group := range groups {
+			done += textLength(group)
+			end := utterance.Start + (utterance.End-utterance.Start)*done/total
+			cues = append(cues, cue{Start: start, End: end, Speaker: utterance.Speaker, Lines: group})
+			start = end
+		}
+	}
+	return cues
+}
+
+// JoinSentences appends sentence to text, with a space unless text ends in a
+// script written without spaces. An empty side returns the other unchanged.
+func JoinSentences(text, sentence string) string {
+	if text == "" || sentence == "" || !spacedAfter(text) {
+		return text + sentence
+	}
+	return text + " " + sentence
+}
+
+// spacedAfter reports whether a sentence following text is separated by a space,
+// which scripts written without spaces are not
+func spacedAfter(text string) bool {
+	last, _ := utf8.DecodeLastRuneInString(text)
+	return !isCJK(last) && !isFullWidthTerminator(last)
+}
+
+// textLength returns the number of characters on the lines
+func textLength(lines []string) int {
+	n := 0
+	for _, line := range lines {
+		n += len([]rune(line))
+	}
+	return n
+}
+
+// formatSubtitleTimestamp formats milliseconds as HH:MM:SS followed by sep and
+// the milliseconds, e.g. 00:01:02,345 for SRT
+func formatSubtitleTimestamp(ms int, sep string) string {
+	d := time.Duration(ms) * time.Millisecond
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, sep, ms%1000)
+}
+
+// renderSRT formats the transcription as SubRip subtitles
+func renderSRT(transcription *Result, opts RenderOptions) string {
+	var output strings.Builder
+	for i, c := range subtitleCues(transcription, opts.SpeakerPrefix, opts.optionInt("srt", "line-width"), opts.optionInt("srt", "max-lines")) {
+		output.WriteString(fmt.Sprintf("%d\r\n", i+1))
+		output.WriteString(fmt.Sprintf("%s --> %s\r\n", formatSubtitleTimestamp(c.Start, ","), formatSubtitleTimestamp(c.End, ",")))
+		for _, line := range c.Lines {
+			output.WriteString(line + "\r\n")
+		}
+		output.WriteString("\r\n")
+	}
+	return output.String()
+}
+
+// vttEscaper escapes the characters that have a meaning in WebVTT cue text
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// vttPositions are the cue settings that place a cue; bottom is the default
+var vttPositions = map[string]string{PositionMiddle: " line:50%", PositionTop: " line:0"}
+
+// vttStyles returns a STYLE block that colors and sets the font of each speaker's
+// voice
+func vttStyles(transcription *Result, styles *Styles) string {
+	var rules strings.Builder
+	for _, speaker := range speakerOrder(transcription) {
+		style := styles.For(speaker)
+		var properties []string
+		if style.Color != "" {
+			properties = append(properties, "color: "+style.Color)
+		}
+		if style.Font != "" {
+			properties = append(properties, fmt.Sprintf("font-family: %q", style.Font))
+		}
+		if len(properties) > 0 {
+			rules.WriteString(fmt.Sprintf("::cue(v[voice=%q]) { %s; }\n", "Speaker "+speaker, strings.Join(properties, "; ")))
+		}
+	}
+	if rules.Len() == 0 {
+		return ""
+	}
+	return "STYLE\n" + rules.String() + "\n"
+}
+
+// renderVTT formats the transcription as WebVTT. Speakers are marked with voice
+// spans rather than a text prefix so that players can style them with ::cue(v[voice=...]).
+func renderVTT(transcription *Result, opts RenderOptions) string {
+	var output strings.Builder
+	output.WriteString("WEBVTT\n\n")
+	if opts.Styles != nil {
+		output.WriteString(vttStyles(transcription, opts.Styles))
+	}
+	for i, c := range subtitleCues(transcription, false, opts.optionInt("vtt", "line-width"), opts.optionInt("vtt", "max-lines")) {
+		output.WriteString(fmt.Sprintf("%d\n", i+1))
+		output.WriteString(fmt.Sprintf("%s --> %s%s\n", formatSubtitleTimestamp(c.Start, "."), formatSubtitleTimestamp(c.End, "."),
+			vttPositions[opts.Styles.For(c.Speaker).Position]))
+		text := vttEscaper.Replace(strings.Join(c.Lines, "\n"))
+		// Styles select cues by voice, so styled cues always carry one
+		if (opts.SpeakerPrefix || opts.Styles != nil) && c.Speaker != "" {
+			text = fmt.Sprintf("<v Speaker %s>%s", vttEscaper.Replace(c.Speaker), text)
+		}
+		output.WriteString(text + "\n\n")
+	}
+	return output.String()
+}
+
+// AtPlaybackRate returns a copy of the transcription with its times scaled for
+// playback at rate, so static captions stay in sync with sped-up video
+func AtPlaybackRate(transcription *Result, rate float64) *Result {
+	scaled := *transcription
+	scaled.Utterances = make([]Segment, len(transcription.Utterances))
+	for i, utterance := range transcription.Utterances {
+		utterance.Start = int(float64(utterance.Start) / rate)
+		utterance.End = int(float64(utterance.End) / rate)
+		scaled.Utterances[i] = utterance
+	}
+	return &scaled
+}
@@ -0,0 +1,383 @@
+// Package transcribe is a client for the AssemblyAI transcription API as used by
+// the transcribe command. It uploads media, requests a diarized transcript and
+// waits for it to complete:
+//
+//	client := transcribe.NewClient(os.Getenv("ASSEMBLYAI_API_KEY"))
+//	result, err := client.TranscribeFile(ctx, "meeting.mp3", transcribe.Options{SpeakerLabels: true})
+//
+// The API accepts most audio and video formats, so the file needs no conversion.
+//
+// The package also holds the output formats of the command (Render, Formats) and
+// the editing of results such as Patch. It runs no external programs, so it builds
+// for GOOS=js GOARCH=wasm and a browser front end can format transcripts with the
+// same code as the command.
+package transcribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// DefaultBaseURL is the AssemblyAI v2 API
+const DefaultBaseURL = "https://api.assemblyai.com/v2"
+
+// defaultPollInterval is how often a pending transcript is checked
+const defaultPollInterval = 3 * time.Second
+
+// maxFailedPolls is how many rate-limited or failed polls in a row Wait retries,
+// doubling the wait before each
+const maxFailedPolls = 5
+
+// Segment is a single transcribed utterance with speaker info
+type Segment struct {
+	Speaker    string  `json:"speaker"`
+	Start      int     `json:"start"`
+	End        int     `json:"end"`
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Chapter is an automatically detected chapter
+type Chapter struct {
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Headline string `json:"headline"`
+	Gist     string `json:"gist"`
+	Summary  string `json:"summary"`
+}
+
+// Options are the transcription settings sent with a transcript request
+type Options struct {
+	SpeakerLabels bool   `json:"speaker_labels"`
+	SpeechModel   string `json:"speech_model,omitempty"`
+	// LanguageCode sets the language of the audio instead of leaving it to the
+	// API, e.g. "tr" or "en_us"
+	LanguageCode string `json:"language_code,omitempty"`
+	AutoChapters bool   `json:"auto_chapters,omitempty"`
+	// AudioStartFrom and AudioEndAt limit transcription to part of the audio (ms)
+	AudioStartFrom int `json:"audio_start_from,omitempty"`
+	AudioEndAt     int `json:"audio_end_at,omitempty"`
+	// SpeakersExpected hints the number of speakers for diarization
+	SpeakersExpected  int      `json:"speakers_expected,omitempty"`
+	RedactPII         bool     `json:"redact_pii,omitempty"`
+	RedactPIIPolicies []string `json:"redact_pii_policies,omitempty"`
+	// WordBoost lists names and phrases to favor in recognition, weighted by
+	// BoostParam: "low", "default" or "high"
+	WordBoost  []string `json:"word_boost,omitempty"`
+	BoostParam string   `json:"boost_param,omitempty"`
+}
+
+// request is the body of a transcript request
+type request struct {
+	AudioURL string `json:"audio_url"`
+	Options
+}
+
+// Result is a transcript as returned by the API. Times are in milliseconds.
+type Result struct {
+	ID           string    `json:"id"`
+	Status       string    `json:"status"`
+	AudioURL     string    `json:"audio_url"`
+	Text         string    `json:"text"`
+	Utterances   []Segment `json:"utterances"`
+	Chapters     []Chapter `json:"chapters"`
+	LanguageCode string    `json:"language_code"`
+	Error        string    `json:"error"`
+}
+
+// uploadResponse is the upload endpoint response
+type uploadResponse struct {
+	UploadURL string `json:"upload_url"`
+}
+
+// APIError is returned when the API responds with a non-OK status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Body)
+}
+
+// Temporary reports whether the error is a rate limit or server error that the
+// same request may not meet again
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Client talks to the API. The zero values of the optional fields select the
+// defaults.
+type Client struct {
+	// APIKey authorizes the requests
+	APIKey string
+	// BaseURL is the API endpoint (default DefaultBaseURL)
+	BaseURL string
+	// HTTPClient sends the requests (default http.DefaultClient)
+	HTTPClient *http.Client
+	// PollInterval is how often Wait checks a pending transcript (default 3s)
+	PollInterval time.Duration
+	// OnStatus, if set, is called with the status of a pending transcript each
+	// time Wait checks it
+	OnStatus func(status string)
+	// StallTimeout, if set, cancels a request with ErrStalled when it sends or
+	// receives nothing for this long. Wait retries a stalled poll.
+	StallTimeout time.Duration
+	// OnStall, if set, is called with the error of a stalled poll that Wait retries
+	OnStall func(err error)
+	// OnPollError, if set, is called with the error of a rate-limited or failed
+	// poll that Wait retries
+	OnPollError func(err error)
+}
+
+// NewClient returns a client for the API using apiKey
+func NewClient(apiKey string) *Client {
+	return &Client{APIKey: apiKey}
+}
+
+func (c *Client) baseURL() string {
+	if c.BaseURL != "" {
+		return c.BaseURL
+	}
+	return DefaultBaseURL
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// send sends an authorized request and returns the response, or an *APIError for
+// a non-OK status. With a StallTimeout the request is watched until the response
+// body is closed.
+func (c *Client) send(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	var dog *watchdog
+	if c.StallTimeout > 0 {
+		ctx, dog = startWatchdog(ctx, c.StallTimeout)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL()+path, body)
+	if err != nil {
+		dog.stop()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if dog != nil && req.Body != nil {
+		req.Body = &requestBody{ReadCloser: req.Body, dog: dog}
+	}
+
+	req.Header.Set("Authorization", c.APIKey)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		err = dog.stalled(ctx, err)
+		dog.stop()
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		dog.stop()
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(data)}
+	}
+	if dog != nil {
+		resp.Body = &responseBody{ReadCloser: resp.Body, ctx: ctx, dog: dog}
+	}
+	return resp, nil
+}
+
+// do sends an authorized request and returns the response body
+func (c *Client) do(ctx context.Context, method, path, contentType string, body io.Reader) ([]byte, error) {
+	resp, err := c.send(ctx, method, path, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return data, nil
+}
+
+// Upload uploads media to the API and returns the URL to transcribe it from
+func (c *Client) Upload(ctx context.Context, media io.Reader) (string, error) {
+	body, err := c.do(ctx, "POST", "/upload", "application/octet-stream", media)
+	if err != nil {
+		return "", fmt.Errorf("upload failed with %w", err)
+	}
+
+	var uploadResp uploadResponse
+	if err := json.Unmarshal(body, &uploadResp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	return uploadResp.UploadURL, nil
+}
+
+// UploadFile uploads a media file to the API and returns the URL to transcribe it from
+func (c *Client) UploadFile(ctx context.Context, filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to open audio file: %w", err)
+	}
+	defer file.Close()
+	return c.Upload(ctx, file)
+}
+
+// Submit requests a transcript of the media at audioURL and returns it while it
+// is still queued
+func (c *Client) Submit(ctx context.Context, audioURL string, opts Options) (*Result, error) {
+	jsonData, err := json.Marshal(request{AudioURL: audioURL, Options: opts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	body, err := c.do(ctx, "POST", "/transcript", "application/json", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("transcription request failed with %w", err)
+	}
+
+	var result Result
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &result, nil
+}
+
+// GetRaw returns the exact bytes the API serves for a transcript
+func (c *Client) GetRaw(ctx context.Context, transcriptID string) ([]byte, error) {
+	body, err := c.do(ctx, "GET", "/transcript/"+transcriptID, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetching transcript failed with %w", err)
+	}
+	return body, nil
+}
+
+// Get returns the current state of a transcript
+func (c *Client) Get(ctx context.Context, transcriptID string) (*Result, error) {
+	resp, err := c.send(ctx, "GET", "/transcript/"+transcriptID, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetching transcript failed with %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Decode straight from the body so long transcripts are not buffered twice
+	var result Result
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse polling response: %w", err)
+	}
+	return &result, nil
+}
+
+// Wait polls a transcript until it completes or fails, or ctx is done
+func (c *Client) Wait(ctx context.Context, transcriptID string) (*Result, error) {
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	stalls, failures := 0, 0
+	for {
+		result, err := c.Get(ctx, transcriptID)
+		if errors.Is(err, ErrStalled) && stalls < maxStalledPolls {
+			// Polling is idempotent, so a wedged connection is simply retried
+			stalls++
+			if c.OnStall != nil {
+				c.OnStall(err)
+			}
+			continue
+		}
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.Temporary() && failures < maxFailedPolls {
+			// The transcript is polled again, backing off, rather than submitted anew
+			failures++
+			if c.OnPollError != nil {
+				c.OnPollError(err)
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(interval << failures):
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to poll: %w", err)
+		}
+		stalls, failures = 0, 0
+
+		switch result.Status {
+		case "completed":
+			return result, nil
+		case "error":
+			return nil, fmt.Errorf("transcription failed: %s", result.Error)
+		case "queued", "processing":
+			if c.OnStatus != nil {
+				c.OnStatus(result.Status)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected status: %s", result.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Transcribe requests a transcript of the media at audioURL and waits for it
+func (c *Client) Transcribe(ctx context.Context, audioURL string, opts Options) (*Result, error) {
+	result, err := c.Submit(ctx, audioURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.Wait(ctx, result.ID)
+}
+
+// TranscribeFile uploads a media file and waits for its transcript
+func (c *Client) TranscribeFile(ctx context.Context, filename string, opts Options) (*Result, error) {
+	audioURL, err := c.UploadFile(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	return c.Transcribe(ctx, audioURL, opts)
+}
+
+// Sentences returns the transcript split into sentences with their times. It
+// segments a transcript requested without speaker labels, which has no
+// utterances; the sentences of such a transcript have no speaker.
+func (c *Client) Sentences(ctx context.Context, transcriptID string) ([]Segment, error) {
+	body, err := c.do(ctx, "GET", "/transcript/"+transcriptID+"/sentences", "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetching sentences failed with %w", err)
+	}
+
+	var response struct {
+		Sentences []Segment `json:"sentences"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return response.Sentences, nil
+}
+
+// Delete deletes a transcript and its uploaded audio from the API
+func (c *Client) Delete(ctx context.Context, transcriptID string) error {
+	if _, err := c.do(ctx, "DELETE", "/transcript/"+transcriptID, "", nil); err != nil {
+		return fmt.Errorf("delete failed with %w", err)
+	}
+	return nil
+}
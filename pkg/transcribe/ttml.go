@@ -0,0 +1,124 @@
+package transcribe
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ttmlHeader declares the namespaces, the default style and the bottom region;
+// the %s verbs take the language, the timing parameters, the speaker styles and
+// the other regions
+const ttmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tts="http://www.w3.org/ns/ttml#styling" xmlns:ttp="http://www.w3.org/ns/ttml#parameter" xml:lang="%s"%s>
+  <head>
+    <styling>
+      <style xml:id="default" tts:fontFamily="proportionalSansSerif" tts:fontSize="100%%" tts:color="white" tts:backgroundColor="black" tts:textAlign="center"/>
+%s    </styling>
+    <layout>
+      <region xml:id="bottom" tts:origin="10%% 80%%" tts:extent="80%% 15%%" tts:displayAlign="after"/>
+%s    </layout>
+  </head>
+  <body style="default" region="bottom">
+    <div>
+`
+
+// ttmlRegions are the regions of the positions other than the bottom
+var ttmlRegions = map[string]string{
+	PositionMiddle: `      <region xml:id="middle" tts:origin="10% 42.5%" tts:extent="80% 15%" tts:displayAlign="center"/>` + "\n",
+	PositionTop:    `      <region xml:id="top" tts:origin="10% 5%" tts:extent="80% 15%" tts:displayAlign="before"/>` + "\n",
+}
+
+// ttmlStyles returns the style elements of the speakers with a color or font, the
+// attributes that select each speaker's style and region on a paragraph, and the
+// regions used
+func ttmlStyles(transcription *Result, styles *Styles) (string, map[string]string, string) {
+	var elements strings.Builder
+	attributes := make(map[string]string)
+	used := make(map[string]bool)
+	for i, speaker := range speakerOrder(transcription) {
+		style := styles.For(speaker)
+		var attribute string
+		if style.Color != "" || style.Font != "" {
+			elements.WriteString(fmt.Sprintf(`      <style xml:id="speaker%d"`, i+1))
+			if style.Color != "" {
+				elements.WriteString(fmt.Sprintf(` tts:color="%s"`, XMLEscape(style.Color)))
+			}
+			if style.Font != "" {
+				elements.WriteString(fmt.Sprintf(` tts:fontFamily="%s"`, XMLEscape(style.Font)))
+			}
+			elements.WriteString("/>\n")
+			attribute += fmt.Sprintf(` style="speaker%d"`, i+1)
+		}
+		if _, ok := ttmlRegions[style.Position]; ok {
+			attribute += fmt.Sprintf(` region="%s"`, style.Position)
+			used[style.Position] = true
+		}
+		attributes[speaker] = attribute
+	}
+
+	var regions strings.Builder
+	for _, position := range []string{PositionMiddle, PositionTop} {
+		if used[position] {
+			regions.WriteString(ttmlRegions[position])
+		}
+	}
+	return elements.String(), attributes, regions.String()
+}
+
+// ttmlFrameRate splits a frame rate such as 29.97 into the integer ttp:frameRate
+// and the ttp:frameRateMultiplier used for NTSC rates
+func ttmlFrameRate(fps float64) (int, string) {
+	rounded := int(math.Round(fps))
+	if math.Abs(fps-float64(rounded)) > 0.001 {
+		return rounded, "1000 1001"
+	}
+	return rounded, ""
+}
+
+// ttmlTimestamp formats ms as a clock time, or as a timecode when a frame rate is
+// given
+func ttmlTimestamp(ms int, fps float64) string {
+	if fps <= 0 {
+		return formatSubtitleTimestamp(ms, ".")
+	}
+	return FormatTimecode(ms, fps)
+}
+
+// FormatTimecode formats ms as an HH:MM:SS:FF timecode. The clock part is real
+// time and only the remainder is counted in frames of the effective rate.
+func FormatTimecode(ms int, fps float64) string {
+	rate, _ := ttmlFrameRate(fps)
+	seconds := ms / 1000
+	frames := min(int(float64(ms%1000)/1000.0*fps), rate-1)
+	return fmt.Sprintf("%02d:%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60, frames)
+}
+
+// renderTTML formats the transcription as TTML captions in the shape EBU-TT-D
+// delivery specs expect: one default style, a bottom region and a paragraph per
+// cue, plus a style and region for each speaker that opts.Styles changes
+func renderTTML(transcription *Result, opts RenderOptions) string {
+	lang := languageTag(transcription.LanguageCode)
+	timing := ` ttp:timeBase="media"`
+	if opts.FrameRate > 0 {
+		rate, multiplier := ttmlFrameRate(opts.FrameRate)
+		timing += fmt.Sprintf(` ttp:frameRate="%d"`, rate)
+		if multiplier != "" {
+			timing += fmt.Sprintf(` ttp:frameRateMultiplier="%s"`, multiplier)
+		}
+	}
+
+	var output strings.Builder
+	styles, attributes, regions := ttmlStyles(transcription, opts.Styles)
+	output.WriteString(fmt.Sprintf(ttmlHeader, lang, timing, styles, regions))
+	for i, c := range subtitleCues(transcription, opts.SpeakerPrefix, opts.optionInt("ttml", "line-width"), opts.optionInt("ttml", "max-lines")) {
+		lines := make([]string, len(c.Lines))
+		for j, line := range c.Lines {
+			lines[j] = XMLEscape(line)
+		}
+		output.WriteString(fmt.Sprintf("      <p xml:id=\"c%d\" begin=\"%s\" end=\"%s\"%s>%s</p>\n",
+			i+1, ttmlTimestamp(c.Start, opts.FrameRate), ttmlTimestamp(c.End, opts.FrameRate), attributes[c.Speaker], strings.Join(lines, "<br/>")))
+	}
+	output.WriteString("    </div>\n  </body>\n</tt>\n")
+	return output.String()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"transcribe/pkg/transcribe"
+)
+
+// videoExtensions are played in a video element; anything else gets an audio player
+var videoExtensions = map[string]bool{".mp4": true, ".mov": true, ".mkv": true, ".webm": true, ".m4v": true}
+
+// playerSegment is a line of the interactive transcript
+type playerSegment struct {
+	Start     float64
+	End       float64
+	Timestamp string
+	Speaker   string
+	Class     string
+	Text      string
+}
+
+// playerPage holds what the interactive transcript renders
+type playerPage struct {
+	Title    string
+	Source   template.URL
+	Video    bool
+	Styles   template.CSS
+	Segments []playerSegment
+}
+
+var playerTemplate = template.Must(template.New("player").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 0 auto; padding: 0 1em 2em; }
+#media { position: sticky; top: 0; background: #fff; padding: 1em 0; }
+#media video, #media audio { width: 100%; max-height: 40vh; }
+.segment { padding: 0.4em 0.6em; border-left: 3px solid transparent; cursor: pointer; }
+.segment:hover { background: #f4f4f4; }
+.segment.current { background: #fff3c4; border-left-color: #e0a800; }
+.meta { color: #666; font-size: 0.9em; }
+{{.Styles}}</style>
+</head>
+<body>
+<div id="media">
+<h1>{{.Title}}</h1>
+{{if .Video}}<video id="player" controls src="{{.Source}}"></video>{{else}}<audio id="player" controls src="{{.Source}}"></audio>{{end}}
+</div>
+{{range .Segments}}<div class="segment{{with .Class}} {{.}}{{end}}" data-start="{{.Start}}" data-end="{{.End}}">
+<span class="meta">[{{.Timestamp}}]{{with .Speaker}} Speaker {{.}}{{end}}</span>
+<p>{{.Text}}</p>
+</div>
+{{end}}<script>
+const player = document.getElementById("player");
+const segments = Array.from(document.querySelectorAll(".segment"));
+let current = null;
+segments.forEach(s => s.addEventListener("click", () => {
+  player.currentTime = parseFloat(s.dataset.start);
+  player.play();
+}));
+player.addEventListener("timeupdate", () => {
+  const t = player.currentTime;
+  const next = segments.find(s => t >= parseFloat(s.dataset.start) && t < parseFloat(s.dataset.end));
+  if (next === current) return;
+  if (current) current.classList.remove("current");
+  current = next || null;
+  if (current) {
+    current.classList.add("current");
+    current.scrollIntoView({block: "center", behavior: "smooth"});
+  }
+});
+</script>
+</body>
+</html>
+`))
+
+// savePlayer writes a self-contained HTML transcript that highlights the current
+// segment during playback and seeks on click. With embedAudio the MP3 is inlined
+// as a data URI, otherwise the page links the source media by relative path.
+// The colors and fonts of styles are applied to the lines of each speaker.
+func savePlayer(filename, mediaFile, audioFile string, embedAudio bool, transcription *TranscriptionResponse, styles *transcribe.Styles) error {
+	page := playerPage{Title: strings.TrimSuffix(filepath.Base(mediaFile), filepath.Ext(mediaFile))}
+	if embedAudio {
+		data, err := os.ReadFile(audioFile)
+		if err != nil {
+			return fmt.Errorf("failed to read audio: %w", err)
+		}
+		page.Source = template.URL("data:audio/mpeg;base64," + base64.StdEncoding.EncodeToString(data))
+	} else {
+		source, err := filepath.Rel(filepath.Dir(filename), mediaFile)
+		if err != nil {
+			source = mediaFile
+		}
+		page.Source = template.URL(filepath.ToSlash(source))
+		page.Video = videoExtensions[strings.ToLower(filepath.Ext(mediaFile))]
+	}
+
+	classes := make(map[string]string)
+	var css strings.Builder
+	for _, utterance := range transcription.Utterances {
+		class, ok := classes[utterance.Speaker]
+		if !ok && styles != nil {
+			class = fmt.Sprintf("speaker-%d", len(classes)+1)
+			classes[utterance.Speaker] = class
+			css.WriteString(playerStyle(class, styles.For(utterance.Speaker)))
+		}
+		page.Segments = append(page.Segments, playerSegment{
+			Start:     float64(utterance.Start) / 1000.0,
+			End:       float64(utterance.End) / 1000.0,
+			Timestamp: transcribe.FormatTimestamp(float64(utterance.Start) / 1000.0),
+			Speaker:   utterance.Speaker,
+			Class:     class,
+			Text:      strings.TrimSpace(utterance.Text),
+		})
+	}
+
+	page.Styles = template.CSS(css.String())
+
+	var output bytes.Buffer
+	if err := playerTemplate.Execute(&output, page); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, output.Bytes(), 0644)
+}
+
+// playerStyle returns the CSS rule that styles the lines of a speaker. The styles
+// file is validated, so colors are #rrggbb; fonts are quoted as CSS strings.
+func playerStyle(class string, style transcribe.Style) string {
+	var rule strings.Builder
+	if style.Color != "" {
+		rule.WriteString(fmt.Sprintf(" color: %s;", style.Color))
+	}
+	if style.Font != "" {
+		rule.WriteString(fmt.Sprintf(" font-family: %q, sans-serif;", strings.NewReplacer(`"`, "", `\`, "", "<", "").Replace(style.Font)))
+	}
+	if rule.Len() == 0 {
+		return ""
+	}
+	return fmt.Sprintf(".%s p {%s }\n", class, rule.String())
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"transcribe/pkg/transcribe"
+
+	"gopkg.in/yaml.v3"
+)
+
+// systemPolicyFile is where administrators place the organization-wide policy
+const systemPolicyFile = "/etc/transcribe/policy.yaml"
+
+// defaultRedactPIIPolicies are the entity types redacted when the policy forces
+// redaction without listing them
+var defaultRedactPIIPolicies = []string{
+	"person_name",
+	"phone_number",
+	"email_address",
+	"credit_card_number",
+	"us_social_security_number",
+}
+
+// policy holds organization-wide settings that command line flags cannot override
+type policy struct {
+	// AllowedSpeechModels restricts the speech models that may be requested
+	AllowedSpeechModels []string `yaml:"allowed_speech_models"`
+	// RedactPII forces PII redaction of the transcript text
+	RedactPII bool `yaml:"redact_pii"`
+	// RedactPIIPolicies lists the entity types to redact
+	RedactPIIPolicies []string `yaml:"redact_pii_policies"`
+	// Privacy forces a privacy mode, e.g. "strict"
+	Privacy string `yaml:"privacy"`
+	// DisabledFlags lists flags that may not be used, e.g. terminology or export-snippets
+	DisabledFlags []string `yaml:"disabled_flags"`
+	// AllowedEndpoints lists the API and LeMUR base URLs that may be called, e.g.
+	// https://api.eu.assemblyai.com/v2 and https://api.eu.assemblyai.com/lemur/v3;
+	// without it only the default endpoints are allowed
+	AllowedEndpoints []string `yaml:"allowed_endpoints"`
+}
+
+// loadPolicy reads the policy file, returning nil if there is none
+func loadPolicy(filename string) (*policy, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	var p policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	return &p, nil
+}
+
+// enforcePolicy loads the policy for a command that sends audio or transcripts to
+// the API or another service and checks the flags set on fs and the speech models it will request.
+// It returns nil when there is no policy.
+func enforcePolicy(fs *flag.FlagSet, models ...string) (*policy, error) {
+	p, err := loadPolicy(systemPolicyFile)
+	if err != nil || p == nil {
+		return nil, err
+	}
+	if err := p.checkFlags(fs); err != nil {
+		return nil, err
+	}
+	if err := p.checkModels(models); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// strict reports whether the policy forces strict privacy. It is false for a nil
+// policy.
+func (p *policy) strict() bool {
+	return p != nil && p.Privacy == "strict"
+}
+
+// checkFlags returns an error if any flag set on fs is disabled by the policy
+func (p *policy) checkFlags(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if err == nil && slices.Contains(p.DisabledFlags, f.Name) {
+			err = fmt.Errorf("--%s is disabled by %s", f.Name, systemPolicyFile)
+		}
+	})
+	return err
+}
+
+// checkStages returns an error if the pipeline runs a stage named after a disabled
+// flag, such as terminology
+func (p *policy) checkStages(steps []pipelineStep) error {
+	for _, step := range steps {
+		if slices.Contains(p.DisabledFlags, step.Name) {
+			return fmt.Errorf("the %s pipeline stage is disabled by %s", step.Name, systemPolicyFile)
+		}
+	}
+	return nil
+}
+
+// checkModels returns an error if any of the models is not allowed. An empty model
+// means the API default, which is always allowed.
+func (p *policy) checkModels(models []string) error {
+	if len(p.AllowedSpeechModels) == 0 {
+		return nil
+	}
+	for _, model := range models {
+		if model != "" && !slices.Contains(p.AllowedSpeechModels, model) {
+			return fmt.Errorf("speech model %s is not allowed by %s", model, systemPolicyFile)
+		}
+	}
+	return nil
+}
+
+// checkEndpoints returns an error if any of the base URLs is not allowed
+func (p *policy) checkEndpoints(endpoints ...string) error {
+	allowed := p.AllowedEndpoints
+	if len(allowed) == 0 {
+		allowed = []string{transcribe.DefaultBaseURL, defaultLemurBaseURL}
+	}
+	for _, endpoint := range endpoints {
+		if !slices.ContainsFunc(allowed, func(a string) bool { return strings.TrimSuffix(a, "/") == endpoint }) {
+			return fmt.Errorf("endpoint %s is not allowed by %s", endpoint, systemPolicyFile)
+		}
+	}
+	return nil
+}
+
+// applyRequest forces the policy's settings onto a transcript request
+func (p *policy) applyRequest(request *transcribe.Options) {
+	if !p.RedactPII {
+		return
+	}
+	request.RedactPII = true
+	request.RedactPIIPolicies = p.RedactPIIPolicies
+	if len(request.RedactPIIPolicies) == 0 {
+		request.RedactPIIPolicies = defaultRedactPIIPolicies
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// audioStream is the subset of ffprobe's stream information used by the pre-flight check
+type audioStream struct {
+	Index         int    `json:"index"`
+	CodecName     string `json:"codec_name"`
+	SampleRate    string `json:"sample_rate"`
+	Channels      int    `json:"channels"`
+	ChannelLayout string `json:"channel_layout"`
+	BitRate       string `json:"bit_rate"`
+}
+
+// audioQuality holds the measurements of the pre-flight check
+type audioQuality struct {
+	Streams        []audioStream
+	PeakDB         float64
+	RMSDB          float64
+	NoiseFloorDB   float64
+	FlatFactor     float64
+	LoudnessLUFS   float64
+	hasMeasurement bool
+}
+
+var (
+	astatsPattern   = regexp.MustCompile(`(?m)^\[Parsed_astats[^\]]*\] (Peak level dB|RMS level dB|Noise floor dB|Flat factor): (\S+)`)
+	loudnessPattern = regexp.MustCompile(`(?m)^\s+I:\s+(\S+) LUFS`)
+)
+
+// probeAudioStreams lists the audio streams of mediaFile
+func probeAudioStreams(mediaFile string) ([]audioStream, error) {
+	out, err := exec.Command("ffprobe", "-v", "error", "-select_streams", "a", "-show_entries", "stream=index,codec_name,sample_rate,channels,channel_layout,bit_rate", "-of", "json", mediaFile).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var probe struct {
+		Streams []audioStream `json:"streams"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+	return probe.Streams, nil
+}
+
+// analyzeAudio measures levels, noise floor, clipping and loudness of the first
+// audio stream of mediaFile
+func analyzeAudio(mediaFile string) (*audioQuality, error) {
+	streams, err := probeAudioStreams(mediaFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(streams) == 0 {
+		return nil, fmt.Errorf("no audio stream found in %s", mediaFile)
+	}
+	quality := &audioQuality{Streams: streams}
+
+	cmd := exec.Command("ffmpeg", "-nostats", "-i", mediaFile, "-map", "0:a:0", "-af", "astats=measure_perchannel=none,ebur128", "-f", "null", "-")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, stderr.String())
+	}
+
+	for _, match := range astatsPattern.FindAllStringSubmatch(stderr.String(), -1) {
+		value, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			continue
+		}
+		quality.hasMeasurement = true
+		switch match[1] {
+		case "Peak level dB":
+			quality.PeakDB = value
+		case "RMS level dB":
+			quality.RMSDB = value
+		case "Noise floor dB":
+			quality.NoiseFloorDB = value
+		case "Flat factor":
+			quality.FlatFactor = value
+		}
+	}
+	if matches := loudnessPattern.FindAllStringSubmatch(stderr.String(), -1); len(matches) > 0 {
+		// The last match is ebur128's summary
+		quality.LoudnessLUFS, _ = strconv.ParseFloat(matches[len(matches)-1][1], 64)
+	}
+
+	return quality, nil
+}
+
+// estimatedSNR is the estimated signal-to-noise ratio in dB
+func (q *audioQuality) estimatedSNR() float64 {
+	return q.RMSDB - q.NoiseFloorDB
+}
+
+// suggestions returns actionable advice for problems found in the audio
+func (q *audioQuality) suggestions() []string {
+	var suggestions []string
+	stream := q.Streams[0]
+
+	if len(q.Streams) > 1 {
+		suggestions = append(suggestions, fmt.Sprintf("input has %d audio tracks and only the first one is transcribed; check that it is the right one", len(q.Streams)))
+	}
+	if stream.Channels > 2 {
+		suggestions = append(suggestions, fmt.Sprintf("%d-channel layout (%s) is mixed down during conversion; dialogue may be buried in a single channel", stream.Channels, stream.ChannelLayout))
+	}
+	if rate, err := strconv.Atoi(stream.SampleRate); err == nil && rate < 16000 {
+		suggestions = append(suggestions, fmt.Sprintf("sample rate is %d Hz (narrowband); accuracy will be lower than for wideband audio", rate))
+	}
+	if !q.hasMeasurement {
+		return suggestions
+	}
+	if q.estimatedSNR() < 15 {
+		suggestions = append(suggestions, fmt.Sprintf("estimated SNR is %.1f dB; consider --isolate-voice to remove background noise or music", q.estimatedSNR()))
+	}
+	if q.FlatFactor > 0 || q.PeakDB >= -0.1 {
+		suggestions = append(suggestions, "audio appears to be clipping; distorted passages may be transcribed poorly")
+	}
+	if q.LoudnessLUFS != 0 && q.LoudnessLUFS < -35 {
+		suggestions = append(suggestions, fmt.Sprintf("recording is very quiet (%.1f LUFS); quiet speakers may be missed", q.LoudnessLUFS))
+	}
+
+	return suggestions
+}
+
+// report formats the measurements and suggestions for printing
+func (q *audioQuality) report() string {
+	var output strings.Builder
+	stream := q.Streams[0]
+	output.WriteString("Audio pre-flight report:\n")
+	output.WriteString(fmt.Sprintf("  Stream: %s, %s Hz, %d channels (%s)\n", stream.CodecName, stream.SampleRate, stream.Channels, stream.ChannelLayout))
+	if q.hasMeasurement {
+		output.WriteString(fmt.Sprintf("  Peak: %.1f dB, RMS: %.1f dB, noise floor: %.1f dB, estimated SNR: %.1f dB\n", q.PeakDB, q.RMSDB, q.NoiseFloorDB, q.estimatedSNR()))
+		output.WriteString(fmt.Sprintf("  Loudness: %.1f LUFS\n", q.LoudnessLUFS))
+	}
+
+	suggestions := q.suggestions()
+	if len(suggestions) == 0 {
+		output.WriteString("  No problems found\n")
+	}
+	for _, suggestion := range suggestions {
+		output.WriteString("  - " + suggestion + "\n")
+	}
+	return output.String()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// shredFile overwrites a file with zeros before removing it so the data does not
+// linger on disk
+func shredFile(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	zeros := make([]byte, 64*1024)
+	for remaining := info.Size(); remaining > 0; {
+		n := min(remaining, int64(len(zeros)))
+		if _, err := file.Write(zeros[:n]); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to overwrite %s: %w", path, err)
+		}
+		remaining -= n
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// shredDir shreds every file under dir and then removes it
+func shredDir(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		return shredFile(path)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to shred %s: %w", dir, err)
+	}
+	return os.RemoveAll(dir)
+}
+
+// deleteTranscript deletes a transcript and its uploaded audio from AssemblyAI
+func deleteTranscript(transcriptID, apiKey string) error {
+	return newClient(apiKey).Delete(context.Background(), transcriptID)
+}
+
+// privacyAttestation describes what a strict privacy run retained. remoteDeleted
+// is whether the run deleted the transcript from the API; a run reprocessing a
+// stored response has nothing to delete and does not claim it.
+func privacyAttestation(transcriptID string, remoteDeleted bool, tempFile string, outputs []string) string {
+	var output strings.Builder
+	output.WriteString("\n--- Privacy attestation ---\n")
+	output.WriteString("Mode: strict\n")
+	output.WriteString(fmt.Sprintf("Temporary audio: overwritten and deleted (%s)\n", tempFile))
+	if remoteDeleted {
+		output.WriteString(fmt.Sprintf("Remote transcript and audio: deleted from AssemblyAI (transcript %s)\n", transcriptID))
+	} else {
+		output.WriteString(fmt.Sprintf("Remote transcript and audio: not deleted by this run, which reprocessed a stored response (transcript %s)\n", transcriptID))
+	}
+	output.WriteString("Terminology memory: not updated\n")
+	output.WriteString("Cache/archive: none written\n")
+	output.WriteString(fmt.Sprintf("Retained: %s\n", strings.Join(outputs, ", ")))
+	return output.String()
+}
+
+// trailerFile returns the file that trailing text blocks such as the privacy
+// attestation go to: the transcript itself when it is plain text, otherwise a
+// companion <transcript>.info.txt so that structured outputs stay valid
+func trailerFile(transcriptFile string) string {
+	switch filepath.Ext(transcriptFile) {
+	case ".txt", ".bib":
+		return transcriptFile
+	}
+	return transcriptFile + ".info.txt"
+}
+
+// appendToFile appends text to a file, creating it if needed
+func appendToFile(filename, text string) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// audioProfile is a named preprocessing setup for a recurring recording location
+type audioProfile struct {
+	// Filter is an FFmpeg audio filter graph, e.g. "highpass=f=120,afftdn=nf=-25"
+	Filter string `yaml:"filter"`
+	// Match lists file name patterns the profile is applied to automatically
+	Match []string `yaml:"match,omitempty"`
+}
+
+// profilesPath returns the location of the saved preprocessing profiles
+func profilesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "transcribe", "profiles.yaml"), nil
+}
+
+// loadProfiles reads the saved profiles, returning none if the file does not exist
+func loadProfiles() (map[string]audioProfile, error) {
+	profiles := make(map[string]audioProfile)
+
+	path, err := profilesPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return profiles, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// saveProfiles writes the profiles back to the config directory
+func saveProfiles(profiles map[string]audioProfile) error {
+	path, err := profilesPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(profiles)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// matchProfile returns the name of the first profile, in name order, with a pattern
+// matching the base name of file
+func matchProfile(profiles map[string]audioProfile, file string) string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	base := filepath.Base(file)
+	for _, name := range names {
+		for _, pattern := range profiles[name].Match {
+			if ok, _ := filepath.Match(pattern, base); ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// chainFilters joins FFmpeg filter graphs so they run one after the other
+func chainFilters(filters ...string) string {
+	var nonEmpty []string
+	for _, f := range filters {
+		if f != "" {
+			nonEmpty = append(nonEmpty, f)
+		}
+	}
+	return strings.Join(nonEmpty, ",")
+}
+
+// runProfile implements the profile subcommand
+func runProfile(args []string) error {
+	usage := errors.New("usage: transcribe profile list | save --filter FILTER [--match PATTERN]... <name> | delete <name>")
+	if len(args) < 1 {
+		return usage
+	}
+
+	profiles, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fmt.Printf("%s: %s", name, profiles[name].Filter)
+			if len(profiles[name].Match) > 0 {
+				fmt.Printf(" (auto: %s)", strings.Join(profiles[name].Match, ", "))
+			}
+			fmt.Println()
+		}
+		return nil
+	case "save":
+		fs := flag.NewFlagSet("profile save", flag.ExitOnError)
+		filter := fs.String("filter", "", "FFmpeg audio filter graph, e.g. highpass=f=120,afftdn=nf=-25")
+		var match stringList
+		fs.Var(&match, "match", "apply the profile to files whose name matches this `pattern` (repeatable)")
+		positional := parseArgs(fs, args[1:])
+		if len(positional) != 1 || *filter == "" {
+			return usage
+		}
+		profiles[positional[0]] = audioProfile{Filter: *filter, Match: match}
+		if err := saveProfiles(profiles); err != nil {
+			return err
+		}
+		fmt.Printf("Saved profile %s\n", positional[0])
+		return nil
+	case "delete":
+		if len(args) != 2 {
+			return usage
+		}
+		if _, ok := profiles[args[1]]; !ok {
+			return fmt.Errorf("no such profile: %s", args[1])
+		}
+		delete(profiles, args[1])
+		return saveProfiles(profiles)
+	}
+	return usage
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	// maxBoostTerms is the number of vocabulary terms the API accepts
+	maxBoostTerms = 1000
+	// maxBoostWords is the number of words the API accepts in one term
+	maxBoostWords = 6
+)
+
+// loadPrompt returns the vocabulary hint of --prompt and --prompt-file. A prompt
+// file may hold one term per line and # comments.
+func loadPrompt(prompt, promptFile string) (string, error) {
+	if promptFile == "" {
+		return prompt, nil
+	}
+	data, err := os.ReadFile(promptFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt: %w", err)
+	}
+	var lines []string
+	for line := range strings.Lines(string(data)) {
+		if line, _, _ = strings.Cut(line, "#"); strings.TrimSpace(line) != "" {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	return strings.Join(append(lines, prompt), "\n"), nil
+}
+
+// promptTerms splits a vocabulary hint such as "Kubernetes, Ayşe Yılmaz, p99
+// latency" into the terms boosted by the API. The API has no free-form prompt for
+// every speech model, so the hint is a list of names and phrases; phrases longer
+// than maxBoostWords are skipped with a warning.
+func promptTerms(prompt string) []string {
+	var terms []string
+	for _, term := range strings.FieldsFunc(prompt, func(r rune) bool { return r == ',' || r == ';' || r == '\n' }) {
+		term = strings.Join(strings.Fields(term), " ")
+		switch {
+		case term == "" || slices.Contains(terms, term):
+			continue
+		case len(strings.Fields(term)) > maxBoostWords:
+			warnf("prompt term %q has more than %d words, skipping it", term, maxBoostWords)
+			continue
+		case len(terms) == maxBoostTerms:
+			warnf("prompt has more than %d terms, using the first %d", maxBoostTerms, maxBoostTerms)
+			return terms
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
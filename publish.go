@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// publishSampleRate is the sample rate of published clips, the rate most speech
+// recognition models are trained on
+const publishSampleRate = 16000
+
+// publishedSegment is a row of a published dataset
+type publishedSegment struct {
+	ID       string  `json:"id"`
+	FileName string  `json:"file_name,omitempty"`
+	Source   string  `json:"source"`
+	Speaker  string  `json:"speaker"`
+	Start    float64 `json:"start"`
+	End      float64 `json:"end"`
+	Text     string  `json:"text"`
+}
+
+// runPublish implements the publish subcommand: it stages the segments of one or
+// more transcripts as a Hugging Face dataset with train and test splits and a
+// dataset card, then uploads it with huggingface-cli, which reads the token from
+// HF_TOKEN or its own login.
+func runPublish(args []string) error {
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	to := fs.String("to", "", "publishing target; hf-dataset is the only one")
+	var audio stringList
+	fs.Var(&audio, "audio", "media `file` of the matching transcript whose segments are clipped into the dataset (repeatable, in transcript order)")
+	testSplit := fs.Float64("test-split", 0.1, "share of segments put in the test split")
+	license := fs.String("license", "", "license identifier for the dataset card, e.g. cc-by-4.0")
+	private := fs.Bool("private", true, "create the dataset repository as private; --private=false makes it public")
+	staging := fs.String("staging", "", "directory to stage the dataset in (default: a temporary directory)")
+	dryRun := fs.Bool("dry-run", false, "stage the dataset without uploading it")
+	positional := parseArgs(fs, args)
+	if *to != "hf-dataset" || len(positional) < 2 {
+		return errors.New("usage: transcribe publish --to hf-dataset [flags] <org/name> <transcript.json>...")
+	}
+	repo, transcripts := positional[0], positional[1:]
+	if strings.Count(repo, "/") != 1 {
+		return fmt.Errorf("dataset name must look like org/name: %s", repo)
+	}
+	if len(audio) > 0 && len(audio) != len(transcripts) {
+		return fmt.Errorf("got %d --audio files for %d transcripts", len(audio), len(transcripts))
+	}
+	if *testSplit < 0 || *testSplit >= 1 {
+		return fmt.Errorf("--test-split must be at least 0 and below 1")
+	}
+	policy, err := enforcePolicy(fs)
+	if err != nil {
+		return err
+	}
+	if policy.strict() {
+		return fmt.Errorf("publish copies transcripts and audio to Hugging Face and is not available with the strict privacy of %s", systemPolicyFile)
+	}
+
+	dir := *staging
+	if dir == "" {
+		var err error
+		dir, err = os.MkdirTemp("", "transcribe-publish-*")
+		if err != nil {
+			return fmt.Errorf("failed to create staging directory: %w", err)
+		}
+		if !*dryRun {
+			defer os.RemoveAll(dir)
+		}
+	}
+
+	splits, err := stageDataset(dir, transcripts, audio, *testSplit)
+	if err != nil {
+		return err
+	}
+	card := datasetCard(repo, *license, len(audio) > 0, splits)
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte(card), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Dataset staged in: %s (%d train, %d test segments)\n", dir, len(splits["train"]), len(splits["test"]))
+	if *dryRun {
+		return nil
+	}
+
+	uploadArgs := []string{"upload", repo, dir, ".", "--repo-type", "dataset", "--commit-message", "Publish transcribed segments"}
+	if *private {
+		uploadArgs = append(uploadArgs, "--private")
+	}
+	cmd := exec.Command("huggingface-cli", uploadArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("huggingface-cli upload failed: %w", err)
+	}
+	fmt.Printf("Dataset published to: https://huggingface.co/datasets/%s\n", repo)
+	return nil
+}
+
+// stageDataset writes the segments of the transcripts under dir/data/<split>, as
+// metadata.jsonl next to the clips when audio is given (the audiofolder layout)
+// and as <split>.jsonl otherwise. Segments are assigned to a split by a hash of
+// their ID, so publishing again keeps every segment in the same split.
+func stageDataset(dir string, transcripts, audio []string, testShare float64) (map[string][]publishedSegment, error) {
+	splits := make(map[string][]publishedSegment)
+	for n, transcriptFile := range transcripts {
+		transcription, err := loadTranscription(transcriptFile)
+		if err != nil {
+			return nil, err
+		}
+		if len(transcription.Utterances) == 0 {
+			return nil, fmt.Errorf("%s has no speaker segments", transcriptFile)
+		}
+
+		source := strings.TrimSuffix(filepath.Base(transcriptFile), filepath.Ext(transcriptFile))
+		for i, utterance := range transcription.Utterances {
+			text := strings.Join(strings.Fields(utterance.Text), " ")
+			if text == "" || utterance.End <= utterance.Start {
+				continue
+			}
+			segment := publishedSegment{
+				ID:      fmt.Sprintf("%s-%04d", source, i+1),
+				Source:  source,
+				Speaker: utterance.Speaker,
+				Start:   float64(utterance.Start) / 1000.0,
+				End:     float64(utterance.End) / 1000.0,
+				Text:    text,
+			}
+
+			split := "train"
+			hash := fnv.New32a()
+			hash.Write([]byte(segment.ID))
+			if float64(hash.Sum32()%1000) < testShare*1000 {
+				split = "test"
+			}
+
+			if len(audio) > 0 {
+				segment.FileName = segment.ID + ".wav"
+				clipDir := filepath.Join(dir, "data", split)
+				if err := os.MkdirAll(clipDir, 0755); err != nil {
+					return nil, fmt.Errorf("failed to create dataset directory: %w", err)
+				}
+				if err := runFFmpeg(
+					"-ss", fmt.Sprintf("%.3f", segment.Start),
+					"-t", fmt.Sprintf("%.3f", segment.End-segment.Start),
+					"-i", audio[n], "-vn", "-ac", "1", "-ar", fmt.Sprint(publishSampleRate), "-c:a", "pcm_s16le",
+					filepath.Join(clipDir, segment.FileName), "-y"); err != nil {
+					return nil, err
+				}
+			}
+			splits[split] = append(splits[split], segment)
+		}
+	}
+
+	for split, segments := range splits {
+		file := filepath.Join(dir, "data", split+".jsonl")
+		if len(audio) > 0 {
+			file = filepath.Join(dir, "data", split, "metadata.jsonl")
+		}
+		if err := writeJSONLines(file, segments); err != nil {
+			return nil, err
+		}
+	}
+	return splits, nil
+}
+
+// writeJSONLines writes one JSON object per line
+func writeJSONLines(filename string, segments []publishedSegment) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	var output strings.Builder
+	for _, segment := range segments {
+		line, err := json.Marshal(segment)
+		if err != nil {
+			return err
+		}
+		output.Write(line)
+		output.WriteString("\n")
+	}
+	return os.WriteFile(filename, []byte(output.String()), 0644)
+}
+
+// datasetCard returns the README.md of the dataset: YAML metadata declaring the
+// splits for the Hub, followed by a summary of the contents
+func datasetCard(repo, license string, withAudio bool, splits map[string][]publishedSegment) string {
+	names := make([]string, 0, len(splits))
+	for split := range splits {
+		names = append(names, split)
+	}
+	slices.Sort(names)
+	slices.Reverse(names) // train before test
+
+	var output strings.Builder
+	output.WriteString("---\n")
+	if license != "" {
+		output.WriteString(fmt.Sprintf("license: %s\n", license))
+	}
+	output.WriteString("task_categories:\n- automatic-speech-recognition\n")
+	output.WriteString(fmt.Sprintf("pretty_name: %s\n", repo[strings.Index(repo, "/")+1:]))
+	output.WriteString("configs:\n- config_name: default\n  data_files:\n")
+	for _, split := range names {
+		path := fmt.Sprintf("data/%s.jsonl", split)
+		if withAudio {
+			path = fmt.Sprintf("data/%s/*", split)
+		}
+		output.WriteString(fmt.Sprintf("  - split: %s\n    path: %s\n", split, path))
+	}
+	output.WriteString("---\n\n")
+
+	output.WriteString(fmt.Sprintf("# %s\n\n", repo))
+	output.WriteString("Speech segments with speaker labels and timestamps, transcribed with AssemblyAI using transcribe.\n\n")
+	output.WriteString("| Split | Segments | Hours | Speakers |\n|---|---|---|---|\n")
+	for _, split := range names {
+		var seconds float64
+		speakers := make(map[string]bool)
+		for _, segment := range splits[split] {
+			seconds += segment.End - segment.Start
+			speakers[segment.Source+"/"+segment.Speaker] = true
+		}
+		output.WriteString(fmt.Sprintf("| %s | %d | %.2f | %d |\n", split, len(splits[split]), seconds/3600, len(speakers)))
+	}
+
+	output.WriteString("\n## Fields\n\n")
+	output.WriteString("- `id`: segment identifier, `<source>-<index>`\n")
+	if withAudio {
+		output.WriteString(fmt.Sprintf("- `audio`: the segment as %d Hz mono WAV\n", publishSampleRate))
+	}
+	output.WriteString("- `source`: the recording the segment comes from\n")
+	output.WriteString("- `speaker`: speaker label, unique within a recording only\n")
+	output.WriteString("- `start`, `end`: position in the recording in seconds\n")
+	output.WriteString("- `text`: the transcribed text\n")
+	output.WriteString("\nTranscripts are machine generated and may contain errors.\n")
+	return output.String()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"transcribe/pkg/transcribe"
+)
+
+// quote is a quotable statement located in the transcript
+type quote struct {
+	Speaker string `json:"speaker"`
+	Quote   string `json:"quote"`
+	Start   int    `json:"-"`
+	End     int    `json:"-"`
+}
+
+const quotesPrompt = `List up to %d of the most quotable statements per speaker: self-contained sentences
+that make sense out of context, such as strong opinions, memorable phrasings or key facts.
+Copy each statement verbatim from the transcript without changing any words.
+Respond with only a JSON array of objects with the fields "speaker" (the speaker label,
+e.g. "A") and "quote".`
+
+// extractQuotes asks LeMUR for quotable statements and locates each of them in the
+// utterances to attach exact timestamps. Quotes that cannot be found verbatim are
+// dropped, since they would be misquotes.
+func extractQuotes(transcription *TranscriptionResponse, perSpeaker int, model, apiKey string) ([]quote, error) {
+	answer, err := runLemurTask(transcription.ID, fmt.Sprintf(quotesPrompt, perSpeaker), model, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []quote
+	if err := parseLemurJSON(answer, &candidates); err != nil {
+		return nil, err
+	}
+
+	var quotes []quote
+	count := make(map[string]int)
+	for _, candidate := range candidates {
+		utterance, ok := locateText(transcription.Utterances, candidate.Quote)
+		if !ok {
+			warnf("dropping quote not found verbatim in the transcript: %q", candidate.Quote)
+			continue
+		}
+		if count[utterance.Speaker] >= perSpeaker {
+			continue
+		}
+		count[utterance.Speaker]++
+		quotes = append(quotes, quote{Speaker: utterance.Speaker, Quote: strings.TrimSpace(candidate.Quote), Start: utterance.Start, End: utterance.End})
+	}
+	return quotes, nil
+}
+
+// saveQuotes writes the quotes grouped by speaker with their timestamps
+func saveQuotes(filename string, quotes []quote) error {
+	var speakers []string
+	bySpeaker := make(map[string][]quote)
+	for _, q := range quotes {
+		if _, ok := bySpeaker[q.Speaker]; !ok {
+			speakers = append(speakers, q.Speaker)
+		}
+		bySpeaker[q.Speaker] = append(bySpeaker[q.Speaker], q)
+	}
+
+	var output strings.Builder
+	for _, speaker := range speakers {
+		output.WriteString(fmt.Sprintf("Speaker %s:\n", speaker))
+		for _, q := range bySpeaker[speaker] {
+			output.WriteString(fmt.Sprintf("  [%s - %s] \"%s\"\n",
+				transcribe.FormatTimestamp(float64(q.Start)/1000.0), transcribe.FormatTimestamp(float64(q.End)/1000.0), q.Quote))
+		}
+		output.WriteString("\n")
+	}
+	return os.WriteFile(filename, []byte(output.String()), 0644)
+}
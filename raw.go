@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fetchTranscriptRaw returns the exact bytes the API serves for a transcript
+func fetchTranscriptRaw(transcriptID, apiKey string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	return newClient(apiKey).GetRaw(ctx, transcriptID)
+}
+
+// saveRawResponse stores the API response of a transcript gzipped under dir as
+// <transcript-id>.json.gz, which loadTranscription reads back, and returns its path
+func saveRawResponse(dir, transcriptID string, body []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	compressed, err := compressGzip(transcriptID+".json", body)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, transcriptID+".json.gz")
+	return path, os.WriteFile(path, compressed, 0644)
+}
+
+// compressGzip returns data gzipped, recording name as the original file name
+func compressGzip(name string, data []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Name = name
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// decompressIfGzip returns data unchanged unless it is gzip-compressed
+func decompressIfGzip(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"transcribe/pkg/transcribe"
+)
+
+// recordingDate is a best guess of when a recording was made
+type recordingDate struct {
+	Date       time.Time
+	Source     string
+	Confidence float64
+}
+
+var spokenDatePattern = regexp.MustCompile(`(?i)\b(?:today is|today's|it is|it's)\s+(?:(?:monday|tuesday|wednesday|thursday|friday|saturday|sunday),?\s+(?:the\s+)?)?` +
+	`(january|february|march|april|may|june|july|august|september|october|november|december)\s+(?:the\s+)?` +
+	`(\d{1,2}(?:st|nd|rd|th)?|[a-z]+(?:[- ][a-z]+)?)(?:,?\s+(\d{4}))?`)
+
+// ordinalDays maps spoken ordinal day numbers to their value
+var ordinalDays = map[string]int{
+	"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5, "sixth": 6, "seventh": 7,
+	"eighth": 8, "ninth": 9, "tenth": 10, "eleventh": 11, "twelfth": 12, "thirteenth": 13,
+	"fourteenth": 14, "fifteenth": 15, "sixteenth": 16, "seventeenth": 17, "eighteenth": 18,
+	"nineteenth": 19, "twentieth": 20, "thirtieth": 30,
+}
+
+// parseSpokenDay parses "5", "5th", "fifth" or "twenty-first" into a day of the month
+func parseSpokenDay(s string) (int, bool) {
+	s = strings.ToLower(s)
+	if n, err := strconv.Atoi(strings.TrimRight(s, "stndrh")); err == nil {
+		return n, n >= 1 && n <= 31
+	}
+	if n, ok := ordinalDays[s]; ok {
+		return n, true
+	}
+	for prefix, tens := range map[string]int{"twenty": 20, "thirty": 30} {
+		rest, ok := strings.CutPrefix(s, prefix)
+		if !ok {
+			continue
+		}
+		if n, ok := ordinalDays[strings.TrimLeft(rest, "- ")]; ok && n < 10 {
+			return tens + n, tens+n <= 31
+		}
+	}
+	return 0, false
+}
+
+// spokenDate finds a date announced in the transcript, such as "today is March
+// fifth". When no year is spoken, year is used.
+func spokenDate(text string, year int) (time.Time, bool) {
+	match := spokenDatePattern.FindStringSubmatch(text)
+	if match == nil {
+		return time.Time{}, false
+	}
+	month, err := time.Parse("January", strings.ToUpper(match[1][:1])+strings.ToLower(match[1][1:]))
+	if err != nil {
+		return time.Time{}, false
+	}
+	day, ok := parseSpokenDay(match[2])
+	if !ok {
+		// The optional second word may belong to the rest of the sentence
+		day, ok = parseSpokenDay(strings.Fields(match[2])[0])
+	}
+	if !ok {
+		return time.Time{}, false
+	}
+	if match[3] != "" {
+		year, _ = strconv.Atoi(match[3])
+	}
+	return time.Date(year, month.Month(), day, 0, 0, 0, 0, time.Local), true
+}
+
+// containerDate reads the creation_time tag of a media file
+func containerDate(mediaFile string) (time.Time, bool) {
+	out, err := exec.Command("ffprobe", "-v", "error", "-show_entries", "format_tags=creation_time", "-of", "csv=p=0", mediaFile).Output()
+	if err != nil {
+		return time.Time{}, false
+	}
+	date, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(out)))
+	return date.Local(), err == nil
+}
+
+// fileDate returns the modification time of a file, or now if it cannot be read
+func fileDate(filename string) time.Time {
+	if info, err := os.Stat(filename); err == nil {
+		return info.ModTime()
+	}
+	return time.Now()
+}
+
+// sameDay reports whether a and b fall on the same calendar day
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// detectRecordingDate combines the container metadata, a date spoken in the
+// recording and the file modification time into a best guess with a confidence
+func detectRecordingDate(mediaFile string, transcription *TranscriptionResponse) recordingDate {
+	modified := fileDate(mediaFile)
+	created, hasCreated := containerDate(mediaFile)
+
+	year := modified.Year()
+	if hasCreated {
+		year = created.Year()
+	}
+	spoken, hasSpoken := spokenDate(transcribe.TranscriptText(transcription), year)
+
+	switch {
+	case hasCreated && hasSpoken && sameDay(created, spoken):
+		return recordingDate{Date: created, Source: "container metadata and spoken date", Confidence: 0.95}
+	case hasSpoken:
+		// A spoken date is deliberate, so it wins over disagreeing metadata
+		return recordingDate{Date: spoken, Source: "spoken date", Confidence: 0.7}
+	case hasCreated:
+		return recordingDate{Date: created, Source: "container metadata", Confidence: 0.8}
+	}
+	return recordingDate{Date: modified, Source: "file modification time", Confidence: 0.3}
+}
+
+// String formats the date for the transcript header
+func (d recordingDate) String() string {
+	return fmt.Sprintf("%s (confidence %.2f, from %s)", d.Date.Format("2006-01-02"), d.Confidence, d.Source)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+
+	"transcribe/pkg/transcribe"
+)
+
+// regionNameWords is the number of words of an utterance used in a region name
+const regionNameWords = 8
+
+// regionName labels an utterance by speaker and its first few words
+func regionName(utterance Utterance) string {
+	words := strings.Fields(utterance.Text)
+	name := strings.Join(words[:min(regionNameWords, len(words))], " ")
+	if len(words) > regionNameWords {
+		name += "..."
+	}
+	return fmt.Sprintf("Speaker %s: %s", utterance.Speaker, name)
+}
+
+// msToSamples converts milliseconds to a sample position at the given rate
+func msToSamples(ms, sampleRate int) int64 {
+	return int64(ms) * int64(sampleRate) / 1000
+}
+
+// saveRegions writes segment boundaries in the given format: "reaper" (region
+// manager CSV) or "protools" (session text marker listing) as sample positions, or
+// "premiere" (marker CSV) as timecodes at frameRate
+func saveRegions(filename, format string, transcription *TranscriptionResponse, sampleRate int, frameRate float64) error {
+	switch format {
+	case "reaper":
+		return saveReaperRegions(filename, transcription, sampleRate)
+	case "protools":
+		return saveProToolsMarkers(filename, transcription, sampleRate)
+	case "premiere":
+		return savePremiereMarkers(filename, transcription, frameRate)
+	}
+	return fmt.Errorf("unknown region format: %s", format)
+}
+
+// regionsExtension returns the file extension used for a region format
+func regionsExtension(format string) string {
+	switch format {
+	case "protools":
+		return ".markers.txt"
+	case "premiere":
+		return ".markers.csv"
+	}
+	return ".regions.csv"
+}
+
+// saveReaperRegions writes a CSV that Reaper's region manager can import
+func saveReaperRegions(filename string, transcription *TranscriptionResponse, sampleRate int) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write([]string{"#", "Name", "Start", "End", "Length"})
+	for i, utterance := range transcription.Utterances {
+		start := msToSamples(utterance.Start, sampleRate)
+		end := msToSamples(utterance.End, sampleRate)
+		w.Write([]string{
+			fmt.Sprintf("R%d", i+1),
+			regionName(utterance),
+			fmt.Sprint(start),
+			fmt.Sprint(end),
+			fmt.Sprint(end - start),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// saveProToolsMarkers writes a marker listing in the layout of a Pro Tools session
+// text export with sample time references
+func saveProToolsMarkers(filename string, transcription *TranscriptionResponse, sampleRate int) error {
+	var output strings.Builder
+	output.WriteString("M A R K E R S  L I S T I N G\n")
+	output.WriteString("#   \tLOCATION     \tTIME REFERENCE    \tUNITS    \tNAME                             \tCOMMENTS\n")
+	for i, utterance := range transcription.Utterances {
+		location := fmt.Sprintf("%s.%03d", transcribe.FormatTimestamp(float64(utterance.Start)/1000.0), utterance.Start%1000)
+		output.WriteString(fmt.Sprintf("%-4d\t%-13s\t%-18d\t%-9s\t%-33s\t%s\n",
+			i+1, location, msToSamples(utterance.Start, sampleRate), "Samples",
+			"Speaker "+utterance.Speaker, strings.TrimSpace(utterance.Text)))
+	}
+	return os.WriteFile(filename, []byte(output.String()), 0644)
+}
+
+// savePremiereMarkers writes a tab-separated marker list in the column layout of
+// Premiere Pro's marker export, with in and out timecodes at frameRate
+func savePremiereMarkers(filename string, transcription *TranscriptionResponse, frameRate float64) error {
+	var output strings.Builder
+	output.WriteString("Marker Name\tDescription\tIn\tOut\tDuration\tMarker Type\n")
+	for _, utterance := range transcription.Utterances {
+		description := strings.Join(strings.Fields(utterance.Text), " ")
+		output.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\tComment\n",
+			regionName(utterance), description,
+			transcribe.FormatTimecode(utterance.Start, frameRate), transcribe.FormatTimecode(utterance.End, frameRate),
+			transcribe.FormatTimecode(utterance.End-utterance.Start, frameRate)))
+	}
+	return os.WriteFile(filename, []byte(output.String()), 0644)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// reprocessArgs turns the arguments of "transcribe reprocess <run-id> [flags]
+// <video-file>" into those of a regular run that starts from the stored response.
+// The run id is the transcript id of a response kept with --keep-raw-responses,
+// looked up in --raw-dir, or the path of the response file itself.
+func reprocessArgs(args []string) ([]string, error) {
+	usage := errors.New("usage: transcribe reprocess <run-id> [--raw-dir dir] [flags] <video-file>")
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		return nil, usage
+	}
+	runID := args[0]
+
+	rawDir := "."
+	var rest []string
+	for i := 1; i < len(args); i++ {
+		switch {
+		case args[i] == "--raw-dir" || args[i] == "-raw-dir":
+			if i+1 == len(args) {
+				return nil, usage
+			}
+			rawDir = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--raw-dir=") || strings.HasPrefix(args[i], "-raw-dir="):
+			rawDir = args[i][strings.Index(args[i], "=")+1:]
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+
+	response := runID
+	if _, err := os.Stat(response); err != nil {
+		response = filepath.Join(rawDir, runID+".json.gz")
+		if _, err := os.Stat(response); err != nil {
+			return nil, fmt.Errorf("no stored response for run %s in %s", runID, rawDir)
+		}
+	}
+	return append([]string{"--from-response", response}, rest...), nil
+}
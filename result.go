@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"transcribe/pkg/transcribe"
+)
+
+// runResult is the machine-readable summary of a run written by --result-json
+type runResult struct {
+	Input        string   `json:"input"`
+	TranscriptID string   `json:"transcript_id,omitempty"`
+	Outputs      []string `json:"outputs"`
+	Stats        runStats `json:"stats"`
+	// RecordedDate is the detected recording date (YYYY-MM-DD) when --detect-date is used
+	RecordedDate           string  `json:"recorded_date,omitempty"`
+	RecordedDateConfidence float64 `json:"recorded_date_confidence,omitempty"`
+	// NotDiarized is set when the transcript fell back to no speaker labels
+	NotDiarized bool     `json:"not_diarized,omitempty"`
+	Warnings    []string `json:"warnings"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// runStats holds figures about the transcript of a run
+type runStats struct {
+	DurationSeconds  float64 `json:"duration_seconds"`
+	Speakers         int     `json:"speakers"`
+	Segments         int     `json:"segments"`
+	Words            int     `json:"words"`
+	Chapters         int     `json:"chapters"`
+	DiarizationScore *int    `json:"diarization_score,omitempty"`
+	// PeakMemoryBytes and ChildPeakMemoryBytes are the peak resident set sizes of
+	// this process and of its largest child process
+	PeakMemoryBytes      int64 `json:"peak_memory_bytes,omitempty"`
+	ChildPeakMemoryBytes int64 `json:"child_peak_memory_bytes,omitempty"`
+}
+
+// collectStats computes the run statistics of a transcription
+func collectStats(transcription *TranscriptionResponse, diarizationScore *int) runStats {
+	stats := runStats{
+		Segments:         len(transcription.Utterances),
+		Words:            len(strings.Fields(transcribe.TranscriptText(transcription))),
+		Chapters:         len(transcription.Chapters),
+		DiarizationScore: diarizationScore,
+	}
+
+	speakers := make(map[string]bool)
+	for _, utterance := range transcription.Utterances {
+		speakers[utterance.Speaker] = true
+		stats.DurationSeconds = max(stats.DurationSeconds, float64(utterance.End)/1000.0)
+	}
+	stats.Speakers = len(speakers)
+
+	return stats
+}
+
+// writeResult writes result as JSON to filename, or to stdout if filename is "-"
+func writeResult(filename string, result *runResult) error {
+	result.Warnings = append([]string{}, warnings...)
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if filename == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(filename, data, 0644)
+}
@@ -0,0 +1,45 @@
+package main
+
+import "regexp"
+
+// ffmpegRetry is a safer ffmpeg strategy to retry with after a failure whose output
+// matches pattern
+type ffmpegRetry struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// InputArgs are added before -i, on top of the arguments of earlier retries
+	InputArgs []string
+}
+
+// conversionRetries are tried in order, each at most once, when converting the
+// input fails with a matching error
+var conversionRetries = []ffmpegRetry{
+	{
+		Name:      "deeper stream probing",
+		Pattern:   regexp.MustCompile(`(?i)could not find codec parameters|unspecified (sample rate|sample format|size)|analyzeduration|probesize`),
+		InputArgs: []string{"-analyzeduration", "100M", "-probesize", "100M"},
+	},
+	{
+		Name:      "regenerated timestamps",
+		Pattern:   regexp.MustCompile(`(?i)non[- ]monotonic|invalid (pts|dts)|timestamps are unset|DTS .* out of order`),
+		InputArgs: []string{"-fflags", "+genpts+igndts"},
+	},
+	{
+		Name:    "error-tolerant decoding",
+		Pattern: regexp.MustCompile(`(?i)invalid data found|error while decoding|corrupt|invalid frame|error splitting the input`),
+		// -fflags does not accumulate, so repeat the flags of the previous retry
+		InputArgs: []string{"-fflags", "+genpts+igndts+discardcorrupt", "-err_detect", "ignore_err"},
+	},
+}
+
+// nextRetry returns the first retry not yet used whose pattern matches the ffmpeg
+// output, or nil when the failure is not one a different strategy can fix
+func nextRetry(output string, used map[string]bool) *ffmpegRetry {
+	for i := range conversionRetries {
+		retry := &conversionRetries[i]
+		if !used[retry.Name] && retry.Pattern.MatchString(output) {
+			return retry
+		}
+	}
+	return nil
+}
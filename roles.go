@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// roleSets maps each --roles value to the label of the leading speaker and the
+// label of everyone else
+var roleSets = map[string][2]string{
+	"interview": {"host", "guest"},
+	"support":   {"agent", "customer"},
+}
+
+var (
+	// greetingPattern matches how hosts and agents typically open a conversation
+	greetingPattern = regexp.MustCompile(`(?i)\b(welcome|thanks for (joining|coming)|good (morning|afternoon|evening)|my name is|hello|hi there)\b`)
+	// supportPattern matches phrases that are typical for the agent in a support call
+	supportPattern = regexp.MustCompile(`(?i)\b(thank you for calling|how (can|may) i help|is there anything else|ticket|account number|let me check)\b`)
+)
+
+// leadScore rates how much a speaker behaves like the one leading the conversation:
+// they ask a large share of the questions, open the conversation with a greeting and
+// talk less than the others
+func leadScore(talk speakerTalk, opening string, questions int) float64 {
+	score := 0.0
+	if questions > 0 {
+		score += 2 * float64(talk.Questions) / float64(questions)
+	}
+	if greetingPattern.MatchString(opening) {
+		score++
+	}
+	if supportPattern.MatchString(opening) {
+		score++
+	}
+	return score + 1 - talk.Share
+}
+
+// classifyRoles labels each speaker with a role from conversational patterns and
+// returns the role of each speaker label. With kind "auto" support calls are told
+// apart from interviews by typical agent phrases.
+func classifyRoles(utterances []Utterance, kind string) map[string]string {
+	report := buildMeetingReport(utterances)
+	if len(report.Speakers) < 2 {
+		return nil
+	}
+
+	// The opening is everything a speaker says in their first three turns
+	openings := make(map[string]string)
+	turns := make(map[string]int)
+	for i, utterance := range utterances {
+		if i == 0 || utterances[i-1].Speaker != utterance.Speaker {
+			turns[utterance.Speaker]++
+		}
+		if turns[utterance.Speaker] <= 3 {
+			openings[utterance.Speaker] += " " + utterance.Text
+		}
+	}
+
+	if kind == "auto" {
+		kind = "interview"
+		for _, opening := range openings {
+			if supportPattern.MatchString(opening) {
+				kind = "support"
+			}
+		}
+	}
+
+	questions := 0
+	for _, talk := range report.Speakers {
+		questions += talk.Questions
+	}
+	lead, best := "", -1.0
+	for _, talk := range report.Speakers {
+		if score := leadScore(talk, openings[talk.Speaker], questions); score > best {
+			lead, best = talk.Speaker, score
+		}
+	}
+
+	roles := make(map[string]string)
+	for _, talk := range report.Speakers {
+		if talk.Speaker == lead {
+			roles[talk.Speaker] = roleSets[kind][0]
+		} else {
+			roles[talk.Speaker] = roleSets[kind][1]
+		}
+	}
+	return roles
+}
+
+// applyRoles appends the role to the speaker labels, e.g. "A" becomes "A (host)",
+// so that every output shows it
+func applyRoles(transcription *TranscriptionResponse, roles map[string]string) {
+	for i, utterance := range transcription.Utterances {
+		if role, ok := roles[utterance.Speaker]; ok {
+			transcription.Utterances[i].Speaker = fmt.Sprintf("%s (%s)", utterance.Speaker, role)
+		}
+	}
+}
+
+// describeRoles lists the roles in order of first appearance for the status output
+func describeRoles(utterances []Utterance, roles map[string]string) string {
+	var parts []string
+	seen := make(map[string]bool)
+	for _, utterance := range utterances {
+		if !seen[utterance.Speaker] {
+			seen[utterance.Speaker] = true
+			parts = append(parts, fmt.Sprintf("Speaker %s: %s", utterance.Speaker, roles[utterance.Speaker]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
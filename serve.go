@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// runServe implements the serve subcommand: it serves a directory of outputs
+// over HTTP so that --player pages and podcast feeds can load their media, which
+// browsers refuse for pages opened from file:// URLs
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 {
+		return errors.New("usage: transcribe serve [--addr host:port] <dir>")
+	}
+	dir := positional[0]
+
+	fmt.Printf("Serving %s at http://%s/\n", dir, *addr)
+	return http.ListenAndServe(*addr, hideDotfiles(http.FileServer(http.Dir(dir))))
+}
+
+// hideDotfiles answers 404 for any path with an element that starts with a dot,
+// such as the .env holding the API key or a .git directory
+func hideDotfiles(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, element := range strings.Split(r.URL.Path, "/") {
+			if strings.HasPrefix(element, ".") {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
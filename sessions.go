@@ -0,0 +1,42 @@
+package main
+
+import (
+	"time"
+
+	"transcribe/pkg/transcribe"
+)
+
+// splitSessions divides a recording of several back-to-back meetings into one
+// transcription per session, starting a new session wherever nobody speaks for at
+// least gap. Timestamps stay relative to the start of the recording.
+func splitSessions(transcription *TranscriptionResponse, gap time.Duration) []*TranscriptionResponse {
+	if len(transcription.Utterances) == 0 {
+		return []*TranscriptionResponse{transcription}
+	}
+
+	gapMs := int(gap / time.Millisecond)
+	var sessions []*TranscriptionResponse
+	for i, utterance := range transcription.Utterances {
+		if i == 0 || utterance.Start-transcription.Utterances[i-1].End >= gapMs {
+			session := *transcription
+			session.Utterances = nil
+			session.Chapters = nil
+			sessions = append(sessions, &session)
+		}
+		current := sessions[len(sessions)-1]
+		current.Utterances = append(current.Utterances, utterance)
+	}
+
+	for _, session := range sessions {
+		start := session.Utterances[0].Start
+		end := session.Utterances[len(session.Utterances)-1].End
+		for _, chapter := range transcription.Chapters {
+			if chapter.Start >= start && chapter.Start < end {
+				session.Chapters = append(session.Chapters, chapter)
+			}
+		}
+		session.Text = transcribe.TranscriptText(session)
+	}
+
+	return sessions
+}
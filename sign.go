@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// integrityPrefix starts the line that carries the source media checksum
+const integrityPrefix = "Source media SHA-256: "
+
+// sha256File returns the hex SHA-256 of a file
+func sha256File(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// integrityBlock records which media file a transcript was made from
+func integrityBlock(media, digest string) string {
+	var output strings.Builder
+	output.WriteString("\n--- Integrity ---\n")
+	output.WriteString(fmt.Sprintf("Source media: %s\n", filepath.Base(media)))
+	output.WriteString(integrityPrefix + digest + "\n")
+	return output.String()
+}
+
+// signFile writes a detached minisign signature of file next to it and returns
+// the signature path
+func signFile(file, secretKey string) (string, error) {
+	signature := file + ".minisig"
+	cmd := exec.Command("minisign", "-S", "-s", secretKey, "-m", file, "-x", signature)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("minisign failed: %w", err)
+	}
+	return signature, nil
+}
+
+// embeddedDigest returns the source media checksum recorded in a transcript
+func embeddedDigest(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	digest := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line, ok := strings.CutPrefix(scanner.Text(), integrityPrefix); ok {
+			digest = strings.TrimSpace(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if digest == "" {
+		return "", errors.New("transcript has no source media checksum")
+	}
+	return digest, nil
+}
+
+// runVerify implements the verify subcommand
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	publicKey := fs.String("key", "minisign.pub", "minisign public key `file`")
+	positional := parseArgs(fs, args)
+	if len(positional) < 1 || len(positional) > 2 {
+		return errors.New("usage: transcribe verify [--key minisign.pub] <transcript> [media-file]")
+	}
+	transcript := positional[0]
+
+	files := []string{transcript}
+	if trailer := trailerFile(transcript); trailer != transcript {
+		files = append(files, trailer)
+	}
+	for _, file := range files {
+		cmd := exec.Command("minisign", "-V", "-q", "-p", *publicKey, "-m", file, "-x", file+".minisig")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("signature check of %s failed: %s", file, strings.TrimSpace(string(output)))
+		}
+		fmt.Printf("Signature: OK (%s)\n", file+".minisig")
+	}
+
+	digest, err := embeddedDigest(trailerFile(transcript))
+	if err != nil {
+		return err
+	}
+	if len(positional) < 2 {
+		fmt.Printf("Source media SHA-256: %s (pass the media file to check it)\n", digest)
+		return nil
+	}
+
+	actual, err := sha256File(positional[1])
+	if err != nil {
+		return err
+	}
+	if actual != digest {
+		return fmt.Errorf("media checksum mismatch: transcript records %s, %s is %s", digest, positional[1], actual)
+	}
+	fmt.Printf("Source media: OK (%s)\n", positional[1])
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"transcribe/pkg/transcribe"
+)
+
+// snippet is a sampled utterance with the audio file cut for it
+type snippet struct {
+	File      string
+	Start     string
+	End       string
+	Speaker   string
+	Text      string
+	Utterance int
+}
+
+var snippetsPage = template.Must(template.New("snippets").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Transcript spot check</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; }
+.snippet { border-bottom: 1px solid #ddd; padding: 1em 0; }
+.meta { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>Transcript spot check</h1>
+<p>{{len .}} randomly sampled segments. Listen to each one and compare it with the text.</p>
+{{range .}}<div class="snippet">
+<div class="meta">#{{.Utterance}} [{{.Start}} - {{.End}}] Speaker {{.Speaker}}</div>
+<audio controls preload="none" src="{{.File}}"></audio>
+<p>{{.Text}}</p>
+</div>
+{{end}}</body>
+</html>
+`))
+
+// parseSnippetCount parses the --export-snippets value, either "n=20" or "20"
+func parseSnippetCount(value string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(value, "n="))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid snippet count: %s", value)
+	}
+	return n, nil
+}
+
+// exportSnippets cuts the audio of n randomly sampled utterances into dir and writes
+// an index.html pairing each clip with its transcript text
+func exportSnippets(dir, audioFile string, transcription *TranscriptionResponse, n int) error {
+	if len(transcription.Utterances) == 0 {
+		return fmt.Errorf("transcript has no segments to sample")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create snippets directory: %w", err)
+	}
+
+	// Sample without replacement and keep the picks in chronological order
+	picks := rand.Perm(len(transcription.Utterances))[:min(n, len(transcription.Utterances))]
+	slices.Sort(picks)
+
+	var snippets []snippet
+	for i, index := range picks {
+		utterance := transcription.Utterances[index]
+		name := fmt.Sprintf("snippet-%03d.mp3", i+1)
+		if err := cutAudio(audioFile, filepath.Join(dir, name), utterance.Start, utterance.End); err != nil {
+			return err
+		}
+
+		snippets = append(snippets, snippet{
+			File:      name,
+			Start:     transcribe.FormatTimestamp(float64(utterance.Start) / 1000.0),
+			End:       transcribe.FormatTimestamp(float64(utterance.End) / 1000.0),
+			Speaker:   utterance.Speaker,
+			Text:      strings.TrimSpace(utterance.Text),
+			Utterance: index + 1,
+		})
+	}
+
+	var page bytes.Buffer
+	if err := snippetsPage.Execute(&page, snippets); err != nil {
+		return fmt.Errorf("failed to render snippets page: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, "index.html"), page.Bytes(), 0644)
+}
+
+// cutAudio extracts the audio between start and end (milliseconds) into an MP3 file
+func cutAudio(audioFile, outFile string, start, end int) error {
+	cmd := exec.Command("ffmpeg",
+		"-ss", fmt.Sprintf("%.3f", float64(start)/1000.0),
+		"-t", fmt.Sprintf("%.3f", float64(end-start)/1000.0),
+		"-i", audioFile, "-vn", "-acodec", "libmp3lame", "-q:a", "2", outFile, "-y")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, stderr.String())
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"transcribe/pkg/transcribe"
+)
+
+// splitMode describes how --split-output divides the transcript
+type splitMode struct {
+	By string // "chapter", "hour" or "speaker-turns"
+	// Turns is the number of speaker turns per file in speaker-turns mode
+	Turns int
+}
+
+// splitPart is a run of consecutive utterances written to its own file
+type splitPart struct {
+	Title      string
+	Utterances []Utterance
+}
+
+// parseSplitMode parses values like "by=hour" or "by=speaker-turns=500"
+func parseSplitMode(value string) (splitMode, error) {
+	by := strings.TrimPrefix(value, "by=")
+	switch {
+	case by == "chapter", by == "hour":
+		return splitMode{By: by}, nil
+	case strings.HasPrefix(by, "speaker-turns="):
+		turns, err := strconv.Atoi(strings.TrimPrefix(by, "speaker-turns="))
+		if err != nil || turns <= 0 {
+			return splitMode{}, fmt.Errorf("invalid speaker turn count: %s", value)
+		}
+		return splitMode{By: "speaker-turns", Turns: turns}, nil
+	}
+	return splitMode{}, fmt.Errorf("invalid split mode: %s", value)
+}
+
+// splitUtterances divides the utterances into parts according to mode
+func splitUtterances(transcription *TranscriptionResponse, mode splitMode) ([]splitPart, error) {
+	// key returns a value that changes whenever a new part should start
+	var key func(i int, utterance Utterance) int
+	var title func(key int) string
+
+	switch mode.By {
+	case "chapter":
+		if len(transcription.Chapters) == 0 {
+			return nil, fmt.Errorf("transcript has no chapters, run with --chapters")
+		}
+		key = func(_ int, utterance Utterance) int {
+			index := 0
+			for i, chapter := range transcription.Chapters {
+				if chapter.Start <= utterance.Start {
+					index = i
+				}
+			}
+			return index
+		}
+		title = func(key int) string { return transcription.Chapters[key].Headline }
+	case "hour":
+		key = func(_ int, utterance Utterance) int { return utterance.Start / int(time.Hour/time.Millisecond) }
+		title = func(key int) string { return fmt.Sprintf("Hour %d", key+1) }
+	case "speaker-turns":
+		key = func(i int, _ Utterance) int { return i / mode.Turns }
+		title = func(key int) string {
+			return fmt.Sprintf("Turns %d-%d", key*mode.Turns+1, (key+1)*mode.Turns)
+		}
+	}
+
+	var parts []splitPart
+	last := -1
+	for i, utterance := range transcription.Utterances {
+		k := key(i, utterance)
+		if len(parts) == 0 || k != last {
+			parts = append(parts, splitPart{Title: title(k)})
+			last = k
+		}
+		parts[len(parts)-1].Utterances = append(parts[len(parts)-1].Utterances, utterance)
+	}
+
+	return parts, nil
+}
+
+// saveSplitTranscription writes each part to a numbered file next to outputFile and an
+// index file listing them, returning the path of the index file
+func saveSplitTranscription(outputFile, format string, transcription *TranscriptionResponse, opts transcribe.RenderOptions, mode splitMode) (string, error) {
+	if len(transcription.Utterances) == 0 {
+		return "", fmt.Errorf("transcript has no segments to split")
+	}
+
+	parts, err := splitUtterances(transcription, mode)
+	if err != nil {
+		return "", err
+	}
+
+	ext := filepath.Ext(outputFile)
+	base := strings.TrimSuffix(outputFile, ext)
+
+	var index strings.Builder
+	for i, part := range parts {
+		filename := fmt.Sprintf("%s-%03d%s", base, i+1, ext)
+
+		// Only pass the agenda items that belong in this part
+		end := time.Duration(math.MaxInt64)
+		if i+1 < len(parts) {
+			end = time.Duration(parts[i+1].Utterances[0].Start) * time.Millisecond
+		}
+		partOpts := opts
+		partOpts.Agenda = nil
+		for _, item := range opts.Agenda {
+			if item.Start < end && (i == 0 || item.Start >= time.Duration(part.Utterances[0].Start)*time.Millisecond) {
+				partOpts.Agenda = append(partOpts.Agenda, item)
+			}
+		}
+
+		partTranscription := *transcription
+		partTranscription.Utterances = part.Utterances
+		if err := saveTranscription(filename, format, &partTranscription, partOpts); err != nil {
+			return "", err
+		}
+
+		first, last := part.Utterances[0], part.Utterances[len(part.Utterances)-1]
+		index.WriteString(fmt.Sprintf("%3d. [%s - %s] %s: %s\n", i+1,
+			transcribe.FormatTimestamp(float64(first.Start)/1000.0),
+			transcribe.FormatTimestamp(float64(last.End)/1000.0),
+			part.Title, filepath.Base(filename)))
+	}
+
+	indexFile := base + "-index" + ext
+	if err := os.WriteFile(indexFile, []byte(index.String()), 0644); err != nil {
+		return "", err
+	}
+	return indexFile, nil
+}
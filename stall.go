@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync/atomic"
+	"time"
+
+	"transcribe/pkg/transcribe"
+)
+
+// stallTimeout is how long an FFmpeg process, download or API request may go
+// without progress before it is stopped and retried; zero disables the check
+var stallTimeout = 10 * time.Minute
+
+// stallRetries is how often a stalled FFmpeg process or upload is run again
+const stallRetries = 2
+
+// progress records when a process or transfer last moved
+type progress struct {
+	last atomic.Int64
+}
+
+func newProgress() *progress {
+	p := &progress{}
+	p.touch()
+	return p
+}
+
+func (p *progress) touch() {
+	p.last.Store(time.Now().UnixNano())
+}
+
+// watch calls onStall once progress stops for stallTimeout, until the returned
+// function is called
+func (p *progress) watch(onStall func()) (stop func()) {
+	if stallTimeout <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(max(min(stallTimeout/4, time.Second), time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if time.Since(time.Unix(0, p.last.Load())) >= stallTimeout {
+					onStall()
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// progressWriter passes writes through, recording them as progress
+type progressWriter struct {
+	w        io.Writer
+	progress *progress
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	w.progress.touch()
+	return w.w.Write(b)
+}
+
+// stallError returns the error of a process or transfer that was stopped for
+// making no progress
+func stallError() error {
+	return fmt.Errorf("%w with no progress for %s", transcribe.ErrStalled, stallTimeout)
+}
+
+// runWatched runs cmd, killing it when it writes nothing to stdout or stderr for
+// stallTimeout. FFmpeg reports its progress on stderr a few times a second, so a
+// silent FFmpeg is wedged.
+func runWatched(cmd *exec.Cmd) error {
+	p := newProgress()
+	for _, w := range []*io.Writer{&cmd.Stdout, &cmd.Stderr} {
+		if *w == nil {
+			*w = io.Discard
+		}
+		*w = &progressWriter{w: *w, progress: p}
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	var stalled atomic.Bool
+	stop := p.watch(func() {
+		stalled.Store(true)
+		cmd.Process.Kill()
+	})
+	err := cmd.Wait()
+	stop()
+	if stalled.Load() {
+		return stallError()
+	}
+	return err
+}
+
+// watchContext returns a context that is canceled when p makes no progress for
+// stallTimeout, with a cancel function that ends the watch
+func watchContext(p *progress) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	stop := p.watch(func() { cancel(transcribe.ErrStalled) })
+	return ctx, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// retryStalled calls run, calling it again up to stallRetries times while it
+// fails with a stall
+func retryStalled(what string, run func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := run()
+		if !errors.Is(err, transcribe.ErrStalled) || attempt == stallRetries {
+			return err
+		}
+		warnf("%s stalled with no progress for %s, retrying", what, stallTimeout)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+
+	"transcribe/pkg/transcribe"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stylesColor is the color syntax accepted in a styles file
+var stylesColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// loadStyles reads a styles file that sets the caption color, font and position
+// of every speaker, e.g.
+//
+//	default:
+//	  font: Brand Sans
+//	speakers:
+//	  A: {color: "#ffcc00"}
+//	  Speaker B: {color: "#66ccff", position: top}
+func loadStyles(filename string) (*transcribe.Styles, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read styles: %w", err)
+	}
+
+	var file transcribe.Styles
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse styles: %w", err)
+	}
+
+	styles := &transcribe.Styles{Default: file.Default, Speakers: make(map[string]transcribe.Style)}
+	if err := checkStyle("default", file.Default); err != nil {
+		return nil, err
+	}
+	for speaker, style := range file.Speakers {
+		if err := checkStyle(speaker, style); err != nil {
+			return nil, err
+		}
+		styles.Speakers[normalizeSpeaker(speaker)] = style
+	}
+	return styles, nil
+}
+
+// checkStyle validates the settings of a style
+func checkStyle(name string, style transcribe.Style) error {
+	if style.Color != "" && !stylesColor.MatchString(style.Color) {
+		return fmt.Errorf("style %s: color must be #rrggbb, not %s", name, style.Color)
+	}
+	switch style.Position {
+	case "", transcribe.PositionBottom, transcribe.PositionMiddle, transcribe.PositionTop:
+		return nil
+	}
+	return fmt.Errorf("style %s: position must be bottom, middle or top, not %s", name, style.Position)
+}
+
+// burnSubtitles writes a copy of the video with the ASS subtitles rendered into
+// the picture. ffmpeg runs in a temporary directory holding the subtitles so that
+// their path needs no escaping in the filter graph.
+func burnSubtitles(outputFile, videoFile, subtitles string) error {
+	dir, err := os.MkdirTemp("", "transcribe-burn-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "subtitles.ass"), []byte(subtitles), 0644); err != nil {
+		return err
+	}
+
+	input, err := filepath.Abs(videoFile)
+	if err != nil {
+		return err
+	}
+	output, err := filepath.Abs(outputFile)
+	if err != nil {
+		return err
+	}
+	return retryStalled("ffmpeg", func() error {
+		cmd := exec.Command("ffmpeg", "-i", input, "-vf", "ass=subtitles.ass",
+			"-c:v", "libx264", "-crf", "18", "-preset", "medium", "-c:a", "aac", "-b:a", "192k", output, "-y")
+		cmd.Dir = dir
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		if err := runWatched(cmd); err != nil {
+			return fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, stderr.String())
+		}
+		return nil
+	})
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// terminologyMemory records the canonical spelling of names and terms so that they
+// stay consistent across a series of recordings
+type terminologyMemory struct {
+	// Terms are the confirmed canonical spellings, which are enforced
+	Terms []string `json:"terms"`
+	// Variants maps a term to the misspellings that are replaced by it
+	Variants map[string][]string `json:"variants,omitempty"`
+	// Candidates are names seen in transcripts. They are not enforced until they
+	// are confirmed as terms with "transcribe terminology confirm".
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+// termChange records how often a spelling was replaced by its canonical form
+type termChange struct {
+	From  string
+	To    string
+	Count int
+}
+
+// loadTerminology reads a terminology memory file, returning an empty memory if the
+// file does not exist yet
+func loadTerminology(filename string) (*terminologyMemory, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return &terminologyMemory{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terminology memory: %w", err)
+	}
+
+	var memory terminologyMemory
+	if err := json.Unmarshal(data, &memory); err != nil {
+		return nil, fmt.Errorf("failed to parse terminology memory: %w", err)
+	}
+	return &memory, nil
+}
+
+// save writes the memory back to filename
+func (m *terminologyMemory) save(filename string) error {
+	slices.Sort(m.Terms)
+	slices.Sort(m.Candidates)
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// enforceTerminology applies the memory in filename to the transcription and reports
+// the changes. If learn is set, newly seen names are added as candidates and the
+// memory is saved.
+func enforceTerminology(filename string, transcription *TranscriptionResponse, learn bool) error {
+	memory, err := loadTerminology(filename)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range memory.apply(transcription) {
+		fmt.Fprintf(status, "Terminology: %q -> %q (%d times)\n", change.From, change.To, change.Count)
+	}
+	if !learn {
+		return nil
+	}
+	if learned := memory.learn(transcription); len(learned) > 0 {
+		fmt.Fprintf(status, "Terminology: %d new candidate terms, enforced once confirmed with transcribe terminology confirm %s: %s\n",
+			len(learned), filename, strings.Join(learned, ", "))
+	}
+
+	return memory.save(filename)
+}
+
+// runTerminology implements the terminology subcommand, which edits a terminology
+// memory: "confirm" enforces terms, such as candidates learned from earlier
+// transcripts, and "variant" records misspellings of a term
+func runTerminology(args []string) error {
+	usage := errors.New("usage: transcribe terminology list <file> | confirm <file> <term>... | variant <file> <term> <misspelling>...")
+	if len(args) < 2 {
+		return usage
+	}
+	filename := args[1]
+	memory, err := loadTerminology(filename)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		fmt.Printf("Terms: %s\n", strings.Join(memory.Terms, ", "))
+		for _, term := range memory.Terms {
+			if variants := memory.Variants[term]; len(variants) > 0 {
+				fmt.Printf("  %s <- %s\n", term, strings.Join(variants, ", "))
+			}
+		}
+		fmt.Printf("Candidates: %s\n", strings.Join(memory.Candidates, ", "))
+		return nil
+	case "confirm":
+		if len(args) < 3 {
+			return usage
+		}
+		for _, term := range args[2:] {
+			memory.Candidates = slices.DeleteFunc(memory.Candidates, func(candidate string) bool { return candidate == term })
+			if !slices.Contains(memory.Terms, term) {
+				memory.Terms = append(memory.Terms, term)
+			}
+		}
+		fmt.Printf("Confirmed %d terms\n", len(args)-2)
+	case "variant":
+		if len(args) < 4 {
+			return usage
+		}
+		term := args[2]
+		if !slices.Contains(memory.Terms, term) {
+			return fmt.Errorf("%s is not a confirmed term", term)
+		}
+		if memory.Variants == nil {
+			memory.Variants = make(map[string][]string)
+		}
+		for _, variant := range args[3:] {
+			if !slices.Contains(memory.Variants[term], variant) {
+				memory.Variants[term] = append(memory.Variants[term], variant)
+			}
+		}
+		fmt.Printf("Recorded %d variants of %s\n", len(args)-3, term)
+	default:
+		return usage
+	}
+	return memory.save(filename)
+}
+
+// has reports whether the memory knows word, either as a term or candidate or as
+// part of a multi-word one, ignoring case
+func (m *terminologyMemory) has(word string) bool {
+	return slices.ContainsFunc(slices.Concat(m.Terms, m.Candidates), func(known string) bool {
+		return slices.ContainsFunc(strings.Fields(known), func(part string) bool { return strings.EqualFold(part, word) })
+	})
+}
+
+// spellingPattern matches text spelled as words, with any space or hyphen
+// between the words
+func spellingPattern(text string) string {
+	words := strings.Fields(text)
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	return strings.Join(words, `[\s-]?`)
+}
+
+// apply rewrites the occurrences of the recorded variants of each term, and of the
+// term written with different spaces or hyphens between its words, to the
+// canonical spelling. Matching is case-sensitive so that a name such as "May" does
+// not capitalize the verb, and words are delimited by any non-letter so that terms
+// beginning or ending with a letter such as "Ç" match.
+func (m *terminologyMemory) apply(transcription *TranscriptionResponse) []termChange {
+	counts := make(map[[2]string]int)
+	for _, term := range m.Terms {
+		alternatives := []string{spellingPattern(term)}
+		for _, variant := range m.Variants[term] {
+			alternatives = append(alternatives, spellingPattern(variant))
+		}
+		pattern := regexp.MustCompile(`(?:^|[^\p{L}\p{N}])(` + strings.Join(alternatives, "|") + `)`)
+
+		replace := func(text string) string {
+			return replaceWords(text, pattern, func(match string) string {
+				if match != term {
+					counts[[2]string{match, term}]++
+				}
+				return term
+			})
+		}
+		for i := range transcription.Utterances {
+			transcription.Utterances[i].Text = replace(transcription.Utterances[i].Text)
+		}
+		transcription.Text = replace(transcription.Text)
+	}
+
+	var changes []termChange
+	for key, count := range counts {
+		changes = append(changes, termChange{From: key[0], To: key[1], Count: count})
+	}
+	slices.SortFunc(changes, func(a, b termChange) int { return strings.Compare(a.From, b.From) })
+	return changes
+}
+
+// replaceWords replaces the first group of each match of pattern that is not
+// followed by a letter or digit. The pattern checks the start of the word itself.
+func replaceWords(text string, pattern *regexp.Regexp, replace func(string) string) string {
+	var output strings.Builder
+	last := 0
+	for _, match := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[2], match[3]
+		if next, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && (unicode.IsLetter(next) || unicode.IsDigit(next)) {
+			continue
+		}
+		output.WriteString(text[last:start])
+		output.WriteString(replace(text[start:end]))
+		last = end
+	}
+	output.WriteString(text[last:])
+	return output.String()
+}
+
+// learn adds capitalized words that appear mid-sentence, which are most likely names
+// or terms, to the candidates of the memory and returns the new ones
+func (m *terminologyMemory) learn(transcription *TranscriptionResponse) []string {
+	var learned []string
+	for _, utterance := range transcription.Utterances {
+		sentenceStart := true
+		for _, word := range strings.Fields(utterance.Text) {
+			trimmed := strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+			first := []rune(trimmed + " ")[0]
+			if !sentenceStart && len([]rune(trimmed)) >= 3 && unicode.IsUpper(first) && !m.has(trimmed) {
+				m.Candidates = append(m.Candidates, trimmed)
+				learned = append(learned, trimmed)
+			}
+			sentenceStart = strings.ContainsAny(word[len(word)-1:], ".?!")
+		}
+	}
+	return learned
+}
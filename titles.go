@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// probedStream is a stream of a container as reported by ffprobe
+type probedStream struct {
+	Index     int               `json:"index"`
+	CodecType string            `json:"codec_type"`
+	CodecName string            `json:"codec_name"`
+	Channels  int               `json:"channels"`
+	Duration  string            `json:"duration"`
+	Tags      map[string]string `json:"tags"`
+}
+
+// probedProgram is a program (title) of a multi-program container such as MPEG-TS
+// or a Blu-ray M2TS
+type probedProgram struct {
+	ProgramID int               `json:"program_id"`
+	Tags      map[string]string `json:"tags"`
+	Streams   []probedStream    `json:"streams"`
+}
+
+// probeTitles returns the programs and all streams of mediaFile
+func probeTitles(mediaFile string) ([]probedProgram, []probedStream, error) {
+	out, err := exec.Command("ffprobe", "-v", "error", "-show_programs", "-show_streams",
+		"-show_entries", "program=program_id:program_tags:stream=index,codec_type,codec_name,channels,duration:stream_tags=language,title",
+		"-of", "json", mediaFile).Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var probe struct {
+		Programs []probedProgram `json:"programs"`
+		Streams  []probedStream  `json:"streams"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+	return probe.Programs, probe.Streams, nil
+}
+
+// describeStream summarizes an audio or subtitle stream on one line
+func describeStream(stream probedStream) string {
+	parts := []string{stream.CodecName}
+	if stream.Channels > 0 {
+		parts = append(parts, fmt.Sprintf("%d ch", stream.Channels))
+	}
+	if lang := stream.Tags["language"]; lang != "" {
+		parts = append(parts, lang)
+	}
+	if title := stream.Tags["title"]; title != "" {
+		parts = append(parts, fmt.Sprintf("%q", title))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// titlesReport lists the programs and audio tracks that --title and --audio-track
+// select from, and points out image-based subtitles
+func titlesReport(programs []probedProgram, streams []probedStream) string {
+	var output strings.Builder
+	if len(programs) > 0 {
+		output.WriteString("Titles (--title):\n")
+		for _, program := range programs {
+			name := program.Tags["service_name"]
+			output.WriteString(fmt.Sprintf("  %d %s\n", program.ProgramID, name))
+			for _, stream := range program.Streams {
+				if stream.CodecType == "audio" {
+					output.WriteString(fmt.Sprintf("      stream #%d: %s\n", stream.Index, describeStream(stream)))
+				}
+			}
+		}
+	}
+
+	output.WriteString("Audio tracks (--audio-track):\n")
+	track := 0
+	pgs := 0
+	for _, stream := range streams {
+		switch stream.CodecType {
+		case "audio":
+			output.WriteString(fmt.Sprintf("  %d: stream #%d, %s\n", track, stream.Index, describeStream(stream)))
+			track++
+		case "subtitle":
+			if stream.CodecName == "hdmv_pgs_subtitle" || stream.CodecName == "dvd_subtitle" {
+				pgs++
+			}
+		}
+	}
+	if track == 0 {
+		output.WriteString("  (none)\n")
+	}
+	if pgs > 0 {
+		output.WriteString(fmt.Sprintf("%d image-based subtitle tracks (PGS/VobSub); they cannot be compared with the transcript without OCR\n", pgs))
+	}
+	return output.String()
+}
+
+// trackMap returns the ffmpeg -map specifier for the selected title and audio
+// track, or "" for ffmpeg's default choice
+func trackMap(title, audioTrack int) string {
+	switch {
+	case title >= 0 && audioTrack >= 0:
+		return fmt.Sprintf("0:p:%d:a:%d", title, audioTrack)
+	case title >= 0:
+		return fmt.Sprintf("0:p:%d:a:0", title)
+	case audioTrack >= 0:
+		return fmt.Sprintf("0:a:%d", audioTrack)
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"transcribe/pkg/transcribe"
+)
+
+// fillerWords are counted as fillers when spoken on their own
+var fillerWords = []string{"um", "uh", "er", "ah", "erm", "hmm", "like", "basically", "actually", "literally"}
+
+// fillerPhrases are multi-word fillers
+var fillerPhrases = []string{"you know", "i mean", "kind of", "sort of"}
+
+var vowelGroups = regexp.MustCompile(`[aeiouy]+`)
+
+// speakerVocabulary holds the vocabulary figures of one speaker
+type speakerVocabulary struct {
+	Speaker   string
+	Words     []string
+	Sentences int
+	Counts    map[string]int
+}
+
+// runVocab implements the vocab subcommand
+func runVocab(args []string) error {
+	fs := flag.NewFlagSet("vocab", flag.ExitOnError)
+	top := fs.Int("top", 15, "number of most frequent words listed per speaker")
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 {
+		return errors.New("usage: transcribe vocab [flags] <transcript.json>")
+	}
+
+	transcription, err := loadTranscription(positional[0])
+	if err != nil {
+		return err
+	}
+	if len(transcription.Utterances) == 0 {
+		return errors.New("transcript has no speaker segments")
+	}
+
+	fmt.Print(vocabularyReport(collectVocabulary(transcription.Utterances, transcription.LanguageCode), *top))
+	return nil
+}
+
+// collectVocabulary gathers the words and sentences of each speaker in order of
+// first appearance
+func collectVocabulary(utterances []Utterance, lang string) []*speakerVocabulary {
+	var speakers []*speakerVocabulary
+	index := make(map[string]*speakerVocabulary)
+	for _, utterance := range utterances {
+		v, ok := index[utterance.Speaker]
+		if !ok {
+			v = &speakerVocabulary{Speaker: utterance.Speaker, Counts: make(map[string]int)}
+			index[utterance.Speaker] = v
+			speakers = append(speakers, v)
+		}
+
+		words := wordPattern.FindAllString(strings.ToLower(utterance.Text), -1)
+		v.Words = append(v.Words, words...)
+		for _, word := range words {
+			v.Counts[word]++
+		}
+		v.Sentences += max(1, len(transcribe.SplitSentences(utterance.Text, lang)))
+	}
+	return speakers
+}
+
+// fillerCount returns the number of filler words and phrases in words
+func fillerCount(words []string) int {
+	count := 0
+	for i, word := range words {
+		if slices.Contains(fillerWords, word) {
+			count++
+		}
+		if i+1 < len(words) && slices.Contains(fillerPhrases, word+" "+words[i+1]) {
+			count++
+		}
+	}
+	return count
+}
+
+// syllables estimates the number of syllables of a lowercase English word
+func syllables(word string) int {
+	n := len(vowelGroups.FindAllString(word, -1))
+	if strings.HasSuffix(word, "e") && n > 1 && !strings.HasSuffix(word, "le") {
+		n--
+	}
+	return max(1, n)
+}
+
+// fleschReadingEase computes the Flesch reading ease score; higher is easier
+func fleschReadingEase(words []string, sentences int) float64 {
+	if len(words) == 0 || sentences == 0 {
+		return 0
+	}
+	total := 0
+	for _, word := range words {
+		total += syllables(word)
+	}
+	return 206.835 - 1.015*float64(len(words))/float64(sentences) - 84.6*float64(total)/float64(len(words))
+}
+
+// rankedWords returns the content words of counts by descending frequency
+func rankedWords(counts map[string]int) []string {
+	var words []string
+	for word := range counts {
+		if len([]rune(word)) >= 3 && !stopwords[word] && !slices.Contains(fillerWords, word) {
+			words = append(words, word)
+		}
+	}
+	slices.SortFunc(words, func(a, b string) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return strings.Compare(a, b)
+	})
+	return words
+}
+
+// vocabularyReport formats word frequencies, filler rates, readability and the
+// terms that only a single speaker used
+func vocabularyReport(speakers []*speakerVocabulary, top int) string {
+	var output strings.Builder
+	for _, v := range speakers {
+		output.WriteString(fmt.Sprintf("Speaker %s\n", v.Speaker))
+		output.WriteString(fmt.Sprintf("  Words: %d (%d distinct)\n", len(v.Words), len(v.Counts)))
+
+		fillers := fillerCount(v.Words)
+		rate := 0.0
+		if len(v.Words) > 0 {
+			rate = float64(fillers) * 100 / float64(len(v.Words))
+		}
+		output.WriteString(fmt.Sprintf("  Fillers: %d (%.1f per 100 words)\n", fillers, rate))
+		output.WriteString(fmt.Sprintf("  Flesch reading ease: %.1f\n", fleschReadingEase(v.Words, v.Sentences)))
+
+		ranked := rankedWords(v.Counts)
+		var frequent []string
+		for _, word := range ranked[:min(top, len(ranked))] {
+			frequent = append(frequent, fmt.Sprintf("%s (%d)", word, v.Counts[word]))
+		}
+		output.WriteString(fmt.Sprintf("  Most frequent: %s\n", strings.Join(frequent, ", ")))
+
+		var own []string
+		for _, word := range ranked {
+			if !slices.ContainsFunc(speakers, func(other *speakerVocabulary) bool { return other != v && other.Counts[word] > 0 }) {
+				own = append(own, word)
+			}
+		}
+		output.WriteString(fmt.Sprintf("  Terms only this speaker used: %s\n\n", strings.Join(own[:min(top, len(own))], ", ")))
+	}
+	return output.String()
+}
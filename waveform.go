@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+)
+
+const (
+	// waveformSampleRate is the rate audio is decoded at for rendering
+	waveformSampleRate = 8000
+	// waveformBlockSize is the number of samples reduced to a single peak value
+	waveformBlockSize = waveformSampleRate / 100
+)
+
+// speakerPalette holds the colors assigned to speakers in order of appearance
+var speakerPalette = []color.RGBA{
+	{0x1f, 0x77, 0xb4, 0xff},
+	{0xff, 0x7f, 0x0e, 0xff},
+	{0x2c, 0xa0, 0x2c, 0xff},
+	{0xd6, 0x27, 0x28, 0xff},
+	{0x94, 0x67, 0xbd, 0xff},
+	{0x8c, 0x56, 0x4b, 0xff},
+	{0xe3, 0x77, 0xc2, 0xff},
+	{0x17, 0xbe, 0xcf, 0xff},
+}
+
+// runWaveform implements the waveform subcommand
+func runWaveform(args []string) error {
+	fs := flag.NewFlagSet("waveform", flag.ExitOnError)
+	out := fs.String("out", "waveform.png", "output PNG file")
+	width := fs.Int("width", 1600, "image width in pixels")
+	height := fs.Int("height", 200, "image height in pixels")
+	chapters := fs.Bool("chapters", false, "draw chapter markers")
+	positional := parseArgs(fs, args)
+	if len(positional) != 2 {
+		return errors.New("usage: transcribe waveform [flags] <transcript.json> <audio-file>")
+	}
+	if *width <= 0 || *height <= 0 {
+		return errors.New("--width and --height must be positive")
+	}
+
+	transcription, err := loadTranscription(positional[0])
+	if err != nil {
+		return err
+	}
+
+	peaks, err := readPeaks(positional[1])
+	if err != nil {
+		return err
+	}
+
+	img := renderWaveform(peaks, transcription, *width, *height, *chapters)
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Waveform saved to: %s\n", *out)
+	return nil
+}
+
+// readPeaks decodes audio with FFmpeg and returns the peak amplitude (0-1) of every
+// 10ms block
+func readPeaks(audioFile string) ([]float64, error) {
+	cmd := exec.Command("ffmpeg", "-i", audioFile, "-vn", "-ac", "1", "-ar", fmt.Sprint(waveformSampleRate), "-f", "s16le", "-")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	var peaks []float64
+	reader := bufio.NewReader(stdout)
+	block := make([]int16, waveformBlockSize)
+	for {
+		err := binary.Read(reader, binary.LittleEndian, block)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			cmd.Wait()
+			return nil, fmt.Errorf("failed to read audio: %w", err)
+		}
+
+		var peak int16
+		for _, sample := range block {
+			if sample < 0 {
+				sample = -(sample + 1)
+			}
+			peak = max(peak, sample)
+		}
+		peaks = append(peaks, float64(peak)/32767)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w", err)
+	}
+	if len(peaks) == 0 {
+		return nil, errors.New("no audio decoded")
+	}
+
+	return peaks, nil
+}
+
+// renderWaveform draws the waveform with a tinted background region and colored
+// bars for each utterance, plus optional chapter markers
+func renderWaveform(peaks []float64, transcription *TranscriptionResponse, width, height int, chapters bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	totalMs := float64(len(peaks)) * 1000 * waveformBlockSize / waveformSampleRate
+	column := func(ms int) int {
+		return min(width, max(0, int(float64(ms)/totalMs*float64(width))))
+	}
+
+	// Background and default bar color for audio outside any utterance
+	fill(img, 0, width, 0, height, color.RGBA{0xff, 0xff, 0xff, 0xff})
+	barColors := make([]color.RGBA, width)
+	for x := range barColors {
+		barColors[x] = color.RGBA{0xa0, 0xa0, 0xa0, 0xff}
+	}
+
+	speakerColors := make(map[string]color.RGBA)
+	for _, utterance := range transcription.Utterances {
+		c, ok := speakerColors[utterance.Speaker]
+		if !ok {
+			c = speakerPalette[len(speakerColors)%len(speakerPalette)]
+			speakerColors[utterance.Speaker] = c
+		}
+
+		x0, x1 := column(utterance.Start), column(utterance.End)
+		fill(img, x0, x1, 0, height, tint(c))
+		for x := x0; x < x1; x++ {
+			barColors[x] = c
+		}
+	}
+
+	mid := height / 2
+	for x := 0; x < width; x++ {
+		// Peak of all blocks falling into this column
+		first := x * len(peaks) / width
+		last := max(first+1, (x+1)*len(peaks)/width)
+		var peak float64
+		for _, p := range peaks[first:min(last, len(peaks))] {
+			peak = max(peak, p)
+		}
+
+		half := int(peak * float64(mid))
+		fill(img, x, x+1, mid-half, mid+half+1, barColors[x])
+	}
+
+	if chapters {
+		for _, chapter := range transcription.Chapters {
+			x := column(chapter.Start)
+			fill(img, x, x+2, 0, height, color.RGBA{0x20, 0x20, 0x20, 0xff})
+		}
+	}
+
+	return img
+}
+
+// fill paints the rectangle [x0,x1) x [y0,y1) with c
+func fill(img *image.RGBA, x0, x1, y0, y1 int, c color.RGBA) {
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// tint returns a light version of c suitable for backgrounds
+func tint(c color.RGBA) color.RGBA {
+	lighten := func(v uint8) uint8 { return uint8(int(v) + (0xff-int(v))*4/5) }
+	return color.RGBA{lighten(c.R), lighten(c.G), lighten(c.B), 0xff}
+}